@@ -0,0 +1,404 @@
+// Copyright 2019 - now The https://github.com/nvwa-io/wago Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wago
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin/binding"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Controller methods may take a request struct, e.g:
+// func (t *UserController) Create(req *CreateUserReq) (*UserResp, error)
+// fields are bound by struct tags, later sources override former ones:
+// json:"name"     => JSON body, while Content-Type is application/json
+// form:"name"     => query string and form body, e.g: name=x, name=x,default=y
+// query:"name"    => query string
+// header:"X-Name" => request header
+// uri:"name"      => path param, e.g: /user/:name
+// only tagged fields are bound, nested structs without tags are walked for tagged fields.
+// default values are set before binding, so any source, including JSON body, overrides them.
+// request is responded with 400 while binding fails, and 422 while validation by validate tags fails,
+// both with ValidationErrors as details, e.g: [{"field":"id","rule":"type","message":"id must be a valid int"}]
+const (
+	TAG_JSON   = "json"
+	TAG_FORM   = "form"
+	TAG_QUERY  = "query"
+	TAG_HEADER = "header"
+	TAG_URI    = "uri"
+
+	// max memory of multipart form, others are stored in temporary files
+	MULTIPART_MAX_MEMORY = 32 << 20
+
+	// rules of FieldError while binding fails
+	BIND_RULE_TYPE = "type"
+	BIND_RULE_JSON = "json"
+	BIND_RULE_FORM = "form"
+
+	// field of FieldError while request body is malformed
+	BIND_FIELD_BODY = "body"
+)
+
+// type of request parameter of controller method, nil means no parameter.
+// error is returned while method isn't able to be a handler.
+func handlerParam(m reflect.Type) (reflect.Type, error) {
+	// receiver is the first
+	switch m.NumIn() {
+	case 1:
+		return nil, nil
+	case 2:
+		in := m.In(1)
+		if in.Kind() == reflect.Struct || (in.Kind() == reflect.Ptr && in.Elem().Kind() == reflect.Struct) {
+			return in, nil
+		}
+		return nil, fmt.Errorf("parameter must be struct or pointer to struct, got %s", in)
+	}
+
+	return nil, fmt.Errorf("at most 1 parameter is supported, got %d", m.NumIn()-1)
+}
+
+// fields bound by form, query, header and uri tags, built once at registration
+type bindPlan struct {
+	// fields by tag
+	fields map[string][]bindField
+
+	// fields with default values, e.g: form:"page,default=1"
+	defaults []bindField
+}
+
+type bindField struct {
+	// index path from request struct, pointers on the path are allocated while setting
+	index []int
+
+	// name in request, e.g: page of form:"page"
+	name string
+
+	// default value
+	def    string
+	hasDef bool
+}
+
+// tags bound from request besides JSON body
+var bindTags = []string{TAG_FORM, TAG_QUERY, TAG_HEADER, TAG_URI}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+	timeType            = reflect.TypeOf(time.Time{})
+)
+
+// inspect request struct, error is returned while any tagged field isn't able to be bound
+func newBindPlan(typ reflect.Type) (*bindPlan, error) {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	plan := &bindPlan{fields: make(map[string][]bindField)}
+	if err := plan.walk(typ, nil, map[reflect.Type]bool{}); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// collect tagged fields, nested structs without tags are walked once per path,
+// so that self-referencing types, e.g: type Cat struct{ Parent *Cat }, don't recurse infinitely
+func (t *bindPlan) walk(typ reflect.Type, index []int, visited map[reflect.Type]bool) error {
+	visited[typ] = true
+	defer delete(visited, typ)
+
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		// exported fields, and fields promoted by unexported embedded structs
+		if f.PkgPath != "" && (!f.Anonymous || f.Type.Kind() == reflect.Ptr) {
+			continue
+		}
+		fieldIndex := append(append([]int{}, index...), i)
+
+		tagged := false
+		for _, tag := range bindTags {
+			v, ok := f.Tag.Lookup(tag)
+			if !ok || v == "-" {
+				continue
+			}
+			tagged = true
+
+			opts := strings.Split(v, ",")
+			field := bindField{index: fieldIndex, name: opts[0]}
+			if field.name == "" {
+				field.name = f.Name
+			}
+			for _, opt := range opts[1:] {
+				if strings.HasPrefix(opt, "default=") {
+					field.def, field.hasDef = strings.TrimPrefix(opt, "default="), true
+				}
+			}
+			if !bindable(f.Type) {
+				return fmt.Errorf("field %s of %s can't be bound by %s tag, type %s isn't supported", f.Name, typ.Name(), tag, f.Type)
+			}
+
+			t.fields[tag] = append(t.fields[tag], field)
+			if field.hasDef {
+				t.defaults = append(t.defaults, field)
+			}
+		}
+		if tagged {
+			continue
+		}
+
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct && !bindable(ft) && !visited[ft] {
+			if err := t.walk(ft, fieldIndex, visited); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// whether field is able to be set from strings
+func bindable(typ reflect.Type) bool {
+	if reflect.PtrTo(typ).Implements(textUnmarshalerType) || typ == timeType {
+		return true
+	}
+
+	switch typ.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return bindable(typ.Elem())
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return false
+}
+
+// create request parameter and bind it from request
+func bindParam(c *Context, typ reflect.Type, plan *bindPlan) (reflect.Value, error) {
+	ptr := typ.Kind() == reflect.Ptr
+	if ptr {
+		typ = typ.Elem()
+	}
+
+	v := reflect.New(typ)
+	if err := plan.bind(c, v.Elem()); err != nil {
+		return v, NewHTTPError(http.StatusBadRequest, "invalid request").WithDetails(err).Wrap(err)
+	}
+	// ValidationErrors is responded with 422 by DefaultErrorRenderer, refer to validate.go
+	if err := Validate(v.Interface()); err != nil {
+		return v, err
+	}
+
+	if !ptr {
+		return v.Elem(), nil
+	}
+	return v, nil
+}
+
+// bind request into v by struct tags, defaults are set first, so that any source overrides them.
+// ValidationErrors is returned while any field fails.
+func (t *bindPlan) bind(c *Context, v reflect.Value) ValidationErrors {
+	errs := make(ValidationErrors, 0)
+	for _, f := range t.defaults {
+		field := fieldByIndex(v, f.index)
+		if err := setField(field, []string{f.def}); err != nil {
+			errs = append(errs, typeFieldError(f.name, field.Type()))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	req := c.Request
+	contentType := c.ContentType()
+	if contentType == binding.MIMEJSON && req.Body != nil && req.ContentLength != 0 {
+		if err := json.NewDecoder(req.Body).Decode(v.Addr().Interface()); err != nil && err != io.EOF {
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) && typeErr.Field != "" {
+				return ValidationErrors{typeFieldError(typeErr.Field, typeErr.Type)}
+			}
+			return ValidationErrors{{Field: BIND_FIELD_BODY, Rule: BIND_RULE_JSON, Message: "invalid JSON body, err=" + err.Error()}}
+		}
+	}
+
+	var err error
+	if contentType == binding.MIMEMultipartPOSTForm {
+		err = req.ParseMultipartForm(MULTIPART_MAX_MEMORY)
+	} else {
+		err = req.ParseForm()
+	}
+	if err != nil {
+		return ValidationErrors{{Field: BIND_FIELD_BODY, Rule: BIND_RULE_FORM, Message: "invalid form body, err=" + err.Error()}}
+	}
+
+	params := make(map[string][]string, len(c.Params))
+	for _, p := range c.Params {
+		params[p.Key] = []string{p.Value}
+	}
+
+	sources := map[string]func(name string) []string{
+		TAG_FORM:   func(name string) []string { return req.Form[name] },
+		TAG_QUERY:  func(name string) []string { return req.URL.Query()[name] },
+		TAG_HEADER: func(name string) []string { return req.Header.Values(name) },
+		TAG_URI:    func(name string) []string { return params[name] },
+	}
+	for _, tag := range bindTags {
+		for _, f := range t.fields[tag] {
+			values := sources[tag](f.name)
+			if len(values) == 0 {
+				continue
+			}
+			field := fieldByIndex(v, f.index)
+			if err := setField(field, values); err != nil {
+				errs = append(errs, typeFieldError(f.name, field.Type()))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// field error while value isn't able to be parsed as type of field
+func typeFieldError(name string, typ reflect.Type) FieldError {
+	for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array {
+		if reflect.PtrTo(typ).Implements(textUnmarshalerType) {
+			break
+		}
+		typ = typ.Elem()
+	}
+
+	return FieldError{Field: name, Rule: BIND_RULE_TYPE, Message: fmt.Sprintf("%s must be a valid %s", name, typ)}
+}
+
+// field of struct by index path, nil pointers on the path are allocated
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 {
+			for v.Kind() == reflect.Ptr {
+				if v.IsNil() {
+					v.Set(reflect.New(v.Type().Elem()))
+				}
+				v = v.Elem()
+			}
+		}
+		v = v.Field(x)
+	}
+
+	return v
+}
+
+// set field from request values
+func setField(v reflect.Value, values []string) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setField(v.Elem(), values)
+	}
+
+	switch v.Kind() {
+	case reflect.Slice:
+		if !v.Addr().Type().Implements(textUnmarshalerType) {
+			s := reflect.MakeSlice(v.Type(), len(values), len(values))
+			for i, value := range values {
+				if err := setValue(s.Index(i), value); err != nil {
+					return err
+				}
+			}
+			v.Set(s)
+			return nil
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len() && i < len(values); i++ {
+			if err := setValue(v.Index(i), values[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return setValue(v, values[0])
+}
+
+func setValue(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setValue(v.Elem(), s)
+	}
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("type %s isn't supported", v.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+// Copyright 2019 - now The https://github.com/nvwa-io/wago Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wago
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type bindCat struct {
+	Name   string   `json:"name"`
+	Parent *bindCat `json:"parent"`
+}
+
+type bindPage struct {
+	ID    int      `uri:"id"`
+	Page  int      `json:"page" form:"page,default=1"`
+	Size  int      `json:"size" form:"size,default=20"`
+	Tags  []string `query:"tag"`
+	Token string   `header:"x-token"`
+	Trace struct {
+		ID string `header:"X-Trace-Id"`
+	}
+	Self *bindPage
+}
+
+type bindController struct {
+	Controller
+}
+
+func (t *bindController) Cat(c *bindCat) (*bindCat, error) {
+	return c, nil
+}
+
+func (t *bindController) Page(p bindPage) (bindPage, error) {
+	p.Self = nil
+	return p, nil
+}
+
+func serveBind(t *testing.T, method, path, body string, header map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	app := New(DefaultConfig())
+	typ := reflect.TypeOf(bindController{})
+	app.Server.POST("/cat", app.HandlerWrapper(typ, "Cat"))
+	app.Server.POST("/page/:id", app.HandlerWrapper(typ, "Page"))
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+
+	return w
+}
+
+func TestBindSelfReferencingType(t *testing.T) {
+	w := serveBind(t, http.MethodPost, "/cat?name=x", `{"name":"kitten","parent":{"name":"cat"}}`, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	var cat bindCat
+	if err := json.Unmarshal(w.Body.Bytes(), &cat); err != nil {
+		t.Fatal(err)
+	}
+	if cat.Name != "kitten" || cat.Parent == nil || cat.Parent.Name != "cat" || cat.Parent.Parent != nil {
+		t.Fatalf("unexpected cat: %+v", cat)
+	}
+}
+
+func TestBindSources(t *testing.T) {
+	w := serveBind(t, http.MethodPost, "/page/7?tag=a&tag=b&size=5", `{"page":3}`, map[string]string{
+		"X-Token":    "tk",
+		"x-trace-id": "tr",
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	var p bindPage
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	// page from JSON body isn't overridden by default value, size from query overrides default value
+	if p.ID != 7 || p.Page != 3 || p.Size != 5 || !reflect.DeepEqual(p.Tags, []string{"a", "b"}) ||
+		p.Token != "tk" || p.Trace.ID != "tr" {
+		t.Fatalf("unexpected page: %+v", p)
+	}
+}
+
+func TestBindDefaults(t *testing.T) {
+	w := serveBind(t, http.MethodPost, "/page/1", ``, nil)
+	var p bindPage
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Page != 1 || p.Size != 20 {
+		t.Fatalf("unexpected page: %+v", p)
+	}
+}
+
+func TestBindInvalidValue(t *testing.T) {
+	cases := []struct {
+		path, body string
+		details    ValidationErrors
+	}{
+		{"/page/abc?size=x", `{}`, ValidationErrors{
+			{Field: "size", Rule: BIND_RULE_TYPE, Message: "size must be a valid int"},
+			{Field: "id", Rule: BIND_RULE_TYPE, Message: "id must be a valid int"},
+		}},
+		{"/page/1", `{"page":"x"}`, ValidationErrors{
+			{Field: "page", Rule: BIND_RULE_TYPE, Message: "page must be a valid int"},
+		}},
+	}
+	for _, c := range cases {
+		w := serveBind(t, http.MethodPost, c.path, c.body, nil)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: status = %d, body = %s", c.path, w.Code, w.Body.String())
+		}
+
+		// same shape as details of 422
+		var body struct {
+			Details ValidationErrors `json:"details"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(body.Details, c.details) {
+			t.Fatalf("%s: details = %+v", c.path, body.Details)
+		}
+	}
+
+	w := serveBind(t, http.MethodPost, "/page/1", `{`, nil)
+	if !strings.Contains(w.Body.String(), `"field":"body","rule":"json"`) {
+		t.Fatalf("malformed body: status = %d, body = %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2019 - now The https://github.com/nvwa-io/wago Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// wago command line tool
+//
+// usage:
+//
+//	wago config genkey              generate a key for WAGO_CONFIG_KEY
+//	wago config encrypt [value]     encrypt value, read from stdin if omitted
+//	wago config decrypt <value>     decrypt "enc:v1:..." value
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/nvwa-io/wago/util/secret"
+	"os"
+	"strings"
+)
+
+const usage = `usage:
+  wago config genkey              generate a key for WAGO_CONFIG_KEY
+  wago config encrypt [value]     encrypt value, read from stdin if omitted
+  wago config decrypt <value>     decrypt "enc:v1:..." value
+
+key is read from environment variable WAGO_CONFIG_KEY or WAGO_CONFIG_KEY_FILE.
+`
+
+func main() {
+	if len(os.Args) < 3 || os.Args[1] != "config" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	out, err := runConfig(os.Args[2], os.Args[3:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	fmt.Println(out)
+}
+
+func runConfig(cmd string, args []string) (string, error) {
+	switch cmd {
+	case "genkey":
+		return secret.GenerateKey()
+	case "encrypt":
+		key, err := secret.LoadKey()
+		if err != nil {
+			return "", err
+		}
+		value, err := argOrStdin(args)
+		if err != nil {
+			return "", err
+		}
+		return secret.Encrypt(key, value)
+	case "decrypt":
+		key, err := secret.LoadKey()
+		if err != nil {
+			return "", err
+		}
+		value, err := argOrStdin(args)
+		if err != nil {
+			return "", err
+		}
+		return secret.Decrypt(key, value)
+	}
+
+	return "", fmt.Errorf("unknown command: config %s\n%s", cmd, usage)
+}
+
+// value from first argument, or first line of stdin
+// reading from stdin keeps secrets out of shell history
+func argOrStdin(args []string) (string, error) {
+	if len(args) > 0 {
+		return args[0], nil
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read value from stdin, err=%s", err.Error())
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
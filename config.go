@@ -14,10 +14,17 @@
 package wago
 
 import (
+	"encoding/json"
+	"errors"
 	"flag"
+	"fmt"
 	"github.com/pelletier/go-toml"
 	"github.com/sirupsen/logrus"
-	"log"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 )
 
 const (
@@ -28,6 +35,16 @@ const (
 	ROUTER_MODE_AUTO    = "auto"
 	ROUTER_MODE_COMMENT = "comment"
 
+	// serve app as HTTP server, FastCGI responder or CGI script
+	SERVER_MODE_HTTP = "http"
+	SERVER_MODE_FCGI = "fcgi"
+	SERVER_MODE_CGI  = "cgi"
+
+	// supported config file formats, detected by file extension
+	CONFIG_FORMAT_TOML = "toml"
+	CONFIG_FORMAT_YAML = "yaml"
+	CONFIG_FORMAT_JSON = "json"
+
 	// PanicLevel level, highest level of severity. Logs and then calls panic with the
 	// message passed to Debug, Info, ...
 	LevelPanic = logrus.PanicLevel
@@ -49,39 +66,306 @@ const (
 )
 
 var (
-	AppConfig = &Config{}
+	// configuration of default app, replaced by SetConfig and LoadConfigFromFlag.
+	// it isn't updated by hot reload, since it's read without lock,
+	// use WagoApp.Config() to read reloaded config, e.g: in request handlers.
+	AppConfig = DefaultConfig()
 )
 
-func init() {
-	configFile := flag.String("c", "config/app.toml", "configuration file path")
+// ConfigError is returned while failed to load configuration,
+// use errors.Is(err, os.ErrNotExist) to check whether config file is missing.
+type ConfigError struct {
+	// config file path, empty while loading from reader
+	Path string
+
+	// failed operation, e.g: read, parse, unmarshal
+	Op string
+
+	Err error
+}
+
+func (t *ConfigError) Error() string {
+	if t.Path == "" {
+		return fmt.Sprintf("wago: failed to %s config: %s", t.Op, t.Err.Error())
+	}
+	return fmt.Sprintf("wago: failed to %s config %s: %s", t.Op, t.Path, t.Err.Error())
+}
+
+func (t *ConfigError) Unwrap() error {
+	return t.Err
+}
+
+// LoadConfig loads configuration from file, format is detected by extension: [.toml, .yaml, .yml, .json],
+// and profiles are layered over it:
+// e.g: config/app.toml, then config/app.<RunMode>.toml, then config/app.local.toml.
+// profiles are optional, refer to config_profile.go
+func LoadConfig(path string) (*Config, error) {
+	base, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{path}
+	merged := make(map[string]interface{})
+	mergeConfigMap(merged, base)
+	for _, file := range profileFiles(path, profileRunMode(base)) {
+		m, err := readConfigFile(file)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		mergeConfigMap(merged, m)
+		files = append(files, file)
+	}
+
+	return newConfig(merged, path, files)
+}
+
+// LoadConfigFromReader loads configuration from content, format is TOML by default
+func LoadConfigFromReader(r io.Reader, format ...string) (*Config, error) {
+	f := CONFIG_FORMAT_TOML
+	if len(format) > 0 {
+		f = format[0]
+	}
+
+	m, err := parseConfig(r, "", f)
+	if err != nil {
+		return nil, err
+	}
+
+	return newConfig(m, "", nil)
+}
+
+// MustLoadConfig is like LoadConfig but panics while failed
+func MustLoadConfig(path string) *Config {
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		panic(err)
+	}
+
+	return cfg
+}
+
+// LoadConfigFromFlag is the legacy way to load configuration of default app,
+// the config file is set by command line flag "-c", default is config/app.toml.
+// notice: flag.Parse() is called, so all flags should be defined before.
+func LoadConfigFromFlag() error {
+	if flag.Lookup("c") == nil {
+		flag.String("c", "config/app.toml", "configuration file path")
+	}
 	flag.Parse()
 
-	tree, err := toml.LoadFile(*configFile)
+	cfg, err := LoadConfig(flag.Lookup("c").Value.String())
 	if err != nil {
-		log.Printf("fail to read file: %s \n", err.Error())
-		return
+		return err
+	}
+	return SetConfig(cfg)
+}
+
+// SetConfig replaces configuration of default app and AppConfig,
+// notice: call it before serving, AppConfig is read without lock.
+func SetConfig(cfg *Config) error {
+	if err := WagoApp.SetConfig(cfg); err != nil {
+		return err
 	}
-	err = tree.Unmarshal(AppConfig)
+	AppConfig = cfg
+
+	return nil
+}
+
+// read and parse config file
+func readConfigFile(path string) (map[string]interface{}, error) {
+	format, err := configFormat(path)
 	if err != nil {
-		log.Printf("fail to unmarshal wago config: %s \n", err.Error())
-		return
+		return nil, &ConfigError{Path: path, Op: "read", Err: err}
 	}
-	AppConfig.Tree = *tree
 
-	fullFillConfig()
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, &ConfigError{Path: path, Op: "read", Err: err}
+	}
+	defer f.Close()
+
+	return parseConfig(f, path, format)
 }
 
-// @TODO Full fill more fields
-func fullFillConfig() {
-	if AppConfig.App.ControllerPath == "" {
-		AppConfig.App.ControllerPath = "controller"
+// detect config format by file extension
+func configFormat(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		return CONFIG_FORMAT_TOML, nil
+	case ".yaml", ".yml":
+		return CONFIG_FORMAT_YAML, nil
+	case ".json":
+		return CONFIG_FORMAT_JSON, nil
 	}
 
-	// RunMode / RouterMode
+	return "", fmt.Errorf("unsupported config file extension %q", filepath.Ext(path))
+}
+
+// parse config content to map
+func parseConfig(r io.Reader, path, format string) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	var err error
+	switch format {
+	case CONFIG_FORMAT_TOML:
+		var tree *toml.Tree
+		if tree, err = toml.LoadReader(r); err == nil {
+			m = tree.ToMap()
+		}
+	case CONFIG_FORMAT_YAML:
+		// empty document is allowed
+		if err = yaml.NewDecoder(r).Decode(&m); err == io.EOF {
+			err = nil
+		}
+	case CONFIG_FORMAT_JSON:
+		err = json.NewDecoder(r).Decode(&m)
+	default:
+		err = fmt.Errorf("unsupported config format %q", format)
+	}
+	if err != nil {
+		return nil, &ConfigError{Path: path, Op: "parse", Err: err}
+	}
+
+	return normalizeConfigValue(m).(map[string]interface{}), nil
+}
+
+// create Config from merged config content
+func newConfig(m map[string]interface{}, path string, files []string) (*Config, error) {
+	cfg := DefaultConfig()
+	cfg.path = path
+	cfg.files = files
+
+	// resolve ${ENV}, file:// references and encrypted values
+	resolver := &secretResolver{cfg: cfg}
+	resolver.resolve(m, "")
+	if len(resolver.errs) > 0 {
+		return nil, &ConfigError{Path: path, Op: "interpolate", Err: resolver.errs}
+	}
+
+	cfg.Tree = newConfigTree(m)
+	err := cfg.Tree.Unmarshal(cfg)
+	if err != nil {
+		return nil, &ConfigError{Path: path, Op: "unmarshal", Err: err}
+	}
+
+	err = applyEnvOverrides(cfg, m)
+	if err != nil {
+		return nil, &ConfigError{Path: path, Op: "override", Err: err}
+	}
+
+	fullFillConfig(cfg)
+
+	if err = cfg.Validate(); err != nil {
+		return nil, &ConfigError{Path: path, Op: "validate", Err: err}
+	}
+
+	return cfg, nil
+}
+
+// DefaultConfig returns configuration with default values,
+// config files are decoded over it.
+func DefaultConfig() *Config {
+	return &Config{
+		Tree: newConfigTree(nil),
+		App: App{
+			App:            "wago",
+			RunMode:        RUN_MODE_DEBUG,
+			RouterMode:     ROUTER_MODE_AUTO,
+			ControllerPath: "controller",
+			WatchInterval:  5,
+			Response: Response{
+				CodeField:      "code",
+				MessageField:   "message",
+				DataField:      "data",
+				RequestIdField: "request_id",
+				ItemsField:     "items",
+				TotalField:     "total",
+				PageField:      "page",
+				SizeField:      "size",
+				SuccessMessage: "ok",
+				FailStatus:     200,
+			},
+		},
+		Log: Log{
+			Formatter: "text",
+			Level:     uint32(LevelInfo),
+			MaxSize:   100,
+		},
+		Server: Server{
+			Mode:            SERVER_MODE_HTTP,
+			Port:            8080,
+			MaxHeaderBytes:  1 << 20,
+			ShutdownTimeout: DEFAULT_SHUTDOWN_TIMEOUT,
+			Cors: Cors{
+				AllowMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
+				AllowHeaders: []string{"Origin", "Content-Length", "Content-Type"},
+				MaxAge:       12,
+			},
+			TLS: TLS{
+				MinVersion: "1.2",
+			},
+		},
+	}
+}
+
+// fill values explicitly set to empty in config files
+func fullFillConfig(cfg *Config) {
+	def := DefaultConfig()
+	if cfg.App.App == "" {
+		cfg.App.App = def.App.App
+	}
+	if cfg.App.RunMode == "" {
+		cfg.App.RunMode = def.App.RunMode
+	}
+	if cfg.App.RouterMode == "" {
+		cfg.App.RouterMode = def.App.RouterMode
+	}
+	if cfg.App.ControllerPath == "" {
+		cfg.App.ControllerPath = def.App.ControllerPath
+	}
+	if cfg.App.WatchInterval == 0 {
+		cfg.App.WatchInterval = def.App.WatchInterval
+	}
+	fillString(&cfg.App.Response.CodeField, def.App.Response.CodeField)
+	fillString(&cfg.App.Response.MessageField, def.App.Response.MessageField)
+	fillString(&cfg.App.Response.DataField, def.App.Response.DataField)
+	fillString(&cfg.App.Response.RequestIdField, def.App.Response.RequestIdField)
+	fillString(&cfg.App.Response.ItemsField, def.App.Response.ItemsField)
+	fillString(&cfg.App.Response.TotalField, def.App.Response.TotalField)
+	fillString(&cfg.App.Response.PageField, def.App.Response.PageField)
+	fillString(&cfg.App.Response.SizeField, def.App.Response.SizeField)
+	if cfg.App.Response.FailStatus == 0 {
+		cfg.App.Response.FailStatus = def.App.Response.FailStatus
+	}
+	if cfg.Log.Formatter == "" {
+		cfg.Log.Formatter = def.Log.Formatter
+	}
+	if cfg.Server.Mode == "" {
+		cfg.Server.Mode = def.Server.Mode
+	}
+	if cfg.Server.WriteTimeout == 0 {
+		cfg.Server.WriteTimeout = cfg.Server.WhiteTimeout
+	}
+	if cfg.Server.ShutdownTimeout == 0 {
+		cfg.Server.ShutdownTimeout = def.Server.ShutdownTimeout
+	}
+	if cfg.Server.TLS.MinVersion == "" {
+		cfg.Server.TLS.MinVersion = def.Server.TLS.MinVersion
+	}
+}
+
+func fillString(s *string, def string) {
+	if *s == "" {
+		*s = def
+	}
 }
 
 type Config struct {
-	toml.Tree
+	// merged configuration content, custom sections can be read from it
+	Tree *ConfigTree `json:"-"`
 
 	// app configuration
 	App App
@@ -91,6 +375,17 @@ type Config struct {
 
 	// HTTP server configuration
 	Server Server
+
+	// sources of config values, refer to config_env.go
+	sources map[string]string
+
+	// base config file and loaded config files in merging order
+	path  string
+	files []string
+
+	// keys and values interpolated from secrets, refer to config_secret.go
+	secrets      map[string]bool
+	secretValues []string
 }
 
 type (
@@ -111,11 +406,46 @@ type (
 		// '-' means use snake string as router path, eg: /v1/home-test/hello-world
 		// '_' means use snake string as router path, eg: /v1/home_test/hello_world
 		RouterSep string
+
+		// reload config while config files changed, refer to config_reload.go
+		WatchConfig bool
+
+		// seconds between checking config files, default is 5
+		WatchInterval int
+
+		// include stack of panic in 500 response while RunMode is debug, refer to recovery.go
+		RecoveryStack bool
+
+		// response envelope, [app.response]
+		Response Response
+	}
+
+	// field names and values of response envelope, refer to response.go
+	// field is omitted while its name is "-"
+	Response struct {
+		// default is code, message, data, request_id
+		CodeField      string
+		MessageField   string
+		DataField      string
+		RequestIdField string
+
+		// fields of page data, default is items, total, page, size
+		ItemsField string
+		TotalField string
+		PageField  string
+		SizeField  string
+
+		// code and message of successful responses, default is 0 and "ok"
+		SuccessCode    int
+		SuccessMessage string
+
+		// HTTP status of Fail(), default is 200
+		FailStatus int
 	}
 
 	// log configurations
 	Log struct {
-		// [json,text,logstash,fluentd] supported
+		// [json,text,logstash,fluentd] supported, logstash and fluentd fall back to text
 		Formatter string
 
 		// Log level
@@ -157,17 +487,101 @@ type (
 	// TLSNextProto map[string]func(*Server, *tls.Conn, Handler)
 	// ConnState func(net.Conn, ConnState)
 	// HTTP server configuration
+	// timeouts are in seconds, 0 means no timeout.
 	Server struct {
+		// [http, fcgi, cgi] supported, default is http.
+		// fcgi serves FastCGI on listeners, cgi serves a single request of CGI environment.
+		Mode string
+
 		Port              int
 		Host              string
 		ReadTimeout       int
 		ReadHeaderTimeout int
-		WhiteTimeout      int
+		WriteTimeout      int
 		IdleTimeout       int
 		MaxHeaderBytes    int
 
+		// Deprecated: misspelled WriteTimeout, only used while WriteTimeout is 0.
+		WhiteTimeout int
+
+		// seconds to wait for in-flight requests and shutdown hooks
+		// while receiving SIGINT/SIGTERM, default is 30
+		ShutdownTimeout int
+
+		// number of worker processes sharing tcp/tls listeners by SO_REUSEPORT,
+		// 0 means serving in current process. refer to prefork_unix.go
+		Prefork int
+
 		// HTTP CORS configuration
 		Cors Cors
+
+		// HTTPS configuration
+		TLS TLS
+
+		// HTTP/2 configuration
+		HTTP2 HTTP2
+
+		// listeners served by the same handler, [[server.listeners]],
+		// default is a tcp (or tls while TLS.Enable) listener on Host:Port.
+		// refer to listener.go
+		Listeners []Listener
+	}
+
+	// listener configuration
+	Listener struct {
+		// [tcp, tls, unix, systemd] supported
+		Network string
+
+		// host:port for tcp/tls, socket path for unix,
+		// socket name in LISTEN_FDNAMES for systemd, empty means all sockets passed by systemd
+		Address string
+
+		// file mode of unix socket, e.g: "0660"
+		Perm string
+
+		// serve TLS on unix or systemd listener, configured by [server.tls]
+		TLS bool
+	}
+
+	// HTTPS configuration, [server.tls]
+	TLS struct {
+		Enable bool
+
+		// PEM encoded certificate and private key,
+		// reloaded while receiving SIGHUP.
+		// generated into .wago/certs while RunMode is debug and both are empty, refer to devcert.go
+		CertFile string
+		KeyFile  string
+
+		// minimum TLS version, [1.0, 1.1, 1.2, 1.3] supported, default is 1.2
+		MinVersion string
+
+		// cipher suite names, e.g: TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256
+		// use Go's default cipher suites if empty
+		CipherSuites []string
+
+		// PEM encoded CA bundle to verify client certificates (mTLS)
+		ClientCAFile string
+
+		// [request, require, verify_if_given, require_and_verify] supported,
+		// default is require_and_verify while ClientCAFile is set.
+		ClientAuth string
+	}
+
+	// HTTP/2 configuration, [server.http2]
+	// HTTP/2 is negotiated by ALPN while TLS is enabled, settings below are applied to both TLS and h2c.
+	HTTP2 struct {
+		// serve HTTP/2 over cleartext connections (h2c), e.g: traffic from Envoy in cluster
+		H2C bool
+
+		// max concurrent streams per connection, default is 250 while 0
+		MaxConcurrentStreams uint32
+
+		// max frame size to read, [16384, 16777215], default is 1MB while 0
+		MaxReadFrameSize uint32
+
+		// seconds to close idle connections, default is Server.IdleTimeout while 0
+		IdleTimeout int
 	}
 
 	// HTTP CORS configuration
@@ -177,7 +591,7 @@ type (
 		AllowHeaders     []string
 		ExposeHeaders    []string
 		AllowCredentials bool
-		AllowOriginFunc  func(string) bool
+		AllowOriginFunc  func(string) bool `json:"-"`
 		MaxAge           int
 	}
 )
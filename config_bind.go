@@ -0,0 +1,178 @@
+// Copyright 2019 - now The https://github.com/nvwa-io/wago Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wago
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Custom config sections are bound into structs, e.g:
+//
+//	type PaymentConfig struct {
+//	    Gateway string `required:"true"`
+//	    Timeout int    `default:"30"`
+//	    Methods []string `default:"card,paypal"`
+//	}
+//
+// wago.BindConfig("payment", &PaymentConfig{})
+// field names are matched case-insensitively, or by json tag.
+const (
+	TAG_DEFAULT  = "default"
+	TAG_REQUIRED = "required"
+)
+
+// ConfigFieldError describes a problem of a single config key
+type ConfigFieldError struct {
+	// key path, e.g: payment.gateway
+	Key     string
+	Message string
+}
+
+func (t *ConfigFieldError) Error() string {
+	return fmt.Sprintf("%s: %s", t.Key, t.Message)
+}
+
+// ConfigErrors aggregates all problems found in configuration
+type ConfigErrors []*ConfigFieldError
+
+func (t ConfigErrors) Error() string {
+	list := make([]string, 0, len(t))
+	for _, e := range t {
+		list = append(list, e.Error())
+	}
+
+	return fmt.Sprintf("%d config error(s):\n  %s", len(t), strings.Join(list, "\n  "))
+}
+
+// a struct bound to config section, re-filled while config is replaced
+type configBinding struct {
+	section string
+	v       interface{}
+}
+
+// BindConfig decodes section of default app's config into v,
+// v is re-filled while config is replaced or reloaded, refer to (*Wago).BindConfig.
+func BindConfig(section string, v interface{}) error {
+	return WagoApp.BindConfig(section, v)
+}
+
+// BindConfig decodes section of current app's config into v,
+// v is re-filled while config is replaced or reloaded.
+// notice: v is overwritten in place by the reloading goroutine, reading it in request handlers is a data race.
+// read v only before serving, or copy it in OnConfigChange handler under your own lock, e.g:
+//
+//	app.OnConfigChange(func(old, new *Config) {
+//		mu.Lock()
+//		payment = *bound
+//		mu.Unlock()
+//	})
+func (t *Wago) BindConfig(section string, v interface{}) error {
+	if err := t.Config().Bind(section, v); err != nil {
+		return err
+	}
+	t.bindings = append(t.bindings, configBinding{section: section, v: v})
+
+	return nil
+}
+
+// Bind decodes config section into v, which should be a pointer to struct.
+// `default:"..."` tags are applied to missing keys, and missing `required:"true"` keys
+// are reported as ConfigErrors. v is kept unchanged while failed.
+func (t *Config) Bind(section string, v interface{}) error {
+	nv, err := t.decodeSection(section, v)
+	if err != nil {
+		return err
+	}
+	reflect.ValueOf(v).Elem().Set(nv.Elem())
+
+	return nil
+}
+
+// decode config section into a new value of v's type
+func (t *Config) decodeSection(section string, v interface{}) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return rv, errors.New("wago: config can only be bound to a non-nil pointer to struct")
+	}
+
+	m := make(map[string]interface{})
+	if sub := t.Tree.Sub(section); sub != nil {
+		m = sub.ToMap()
+	}
+
+	nv := reflect.New(rv.Elem().Type())
+	errs := make(ConfigErrors, 0)
+	applyBindingTags(nv.Elem(), m, section, &errs)
+	if len(errs) > 0 {
+		return rv, errs
+	}
+
+	if err := decodeConfigMap(m, nv.Interface()); err != nil {
+		return rv, &ConfigError{Op: "bind " + section, Err: err}
+	}
+
+	return nv, nil
+}
+
+// apply default tags and check required tags of struct fields
+func applyBindingTags(v reflect.Value, m map[string]interface{}, path string, errs *ConfigErrors) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := bindingKey(field)
+		if name == "" {
+			continue
+		}
+		key := path + "." + name
+		raw, exist := getKeyFold(m, name)
+
+		if field.Type.Kind() == reflect.Struct {
+			sub, _ := raw.(map[string]interface{})
+			applyBindingTags(v.Field(i), sub, key, errs)
+			continue
+		}
+
+		if exist {
+			continue
+		}
+		if field.Tag.Get(TAG_REQUIRED) == "true" {
+			*errs = append(*errs, &ConfigFieldError{Key: key, Message: "required key is missing"})
+			continue
+		}
+		if def, ok := field.Tag.Lookup(TAG_DEFAULT); ok {
+			if err := setValueFromString(v.Field(i), def); err != nil {
+				*errs = append(*errs, &ConfigFieldError{Key: key, Message: "invalid default value: " + err.Error()})
+			}
+		}
+	}
+}
+
+// config key of struct field, same as JSON decoding
+func bindingKey(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		name = field.Name
+	}
+
+	return name
+}
@@ -0,0 +1,199 @@
+// Copyright 2019 - now The https://github.com/nvwa-io/wago Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wago
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Environment variables override configuration loaded from file.
+// name of environment variable is ENV_PREFIX and the upper-cased field path joined by '_',
+// e.g:
+// App.RunMode                => WAGO_APP_RUNMODE
+// Log.Level                  => WAGO_LOG_LEVEL
+// Server.Port                => WAGO_SERVER_PORT
+// Server.Cors.AllowOrigins   => WAGO_SERVER_CORS_ALLOWORIGINS
+// list values are separated by ',', e.g: WAGO_SERVER_CORS_ALLOWORIGINS=https://a.com,https://b.com
+const (
+	ENV_PREFIX = "WAGO"
+
+	// sources of configuration values, refer to Config.Source()
+	CONFIG_SOURCE_DEFAULT = "default"
+	CONFIG_SOURCE_FILE    = "file"
+	CONFIG_SOURCE_ENV     = "env"
+)
+
+// ConfigEnvName returns environment variable name of config key, e.g: Server.Port => WAGO_SERVER_PORT
+func ConfigEnvName(key string) string {
+	return ENV_PREFIX + "_" + strings.ToUpper(strings.Replace(key, ".", "_", -1))
+}
+
+// Source returns where the value of config key comes from, [default, file, env],
+// key is the field path, e.g: Server.Cors.AllowOrigins
+func (t *Config) Source(key string) string {
+	if s, ok := t.sources[key]; ok {
+		return s
+	}
+
+	return CONFIG_SOURCE_DEFAULT
+}
+
+// Sources returns sources of all config keys
+func (t *Config) Sources() map[string]string {
+	m := make(map[string]string, len(t.sources))
+	for k, v := range t.sources {
+		m[k] = v
+	}
+
+	return m
+}
+
+// record value sources and apply environment variable overrides
+// raw is the configuration content loaded from file
+func applyEnvOverrides(cfg *Config, raw map[string]interface{}) error {
+	cfg.sources = make(map[string]string)
+
+	v := reflect.ValueOf(cfg).Elem()
+	for _, name := range []string{"App", "Log", "Server"} {
+		err := overrideStruct(cfg, v.FieldByName(name), []string{name}, raw)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func overrideStruct(cfg *Config, v reflect.Value, path []string, raw map[string]interface{}) error {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		fv := v.Field(i)
+		fieldPath := append(append([]string{}, path...), field.Name)
+		if field.PkgPath != "" || field.Anonymous {
+			continue
+		}
+
+		if fv.Kind() == reflect.Struct {
+			if err := overrideStruct(cfg, fv, fieldPath, raw); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !isEnvSupported(fv.Type()) {
+			continue
+		}
+
+		key := strings.Join(fieldPath, ".")
+		if hasKeyFold(raw, fieldPath) {
+			cfg.sources[key] = CONFIG_SOURCE_FILE
+		}
+
+		val, ok := os.LookupEnv(ConfigEnvName(key))
+		if !ok {
+			continue
+		}
+		if err := setValueFromString(fv, val); err != nil {
+			return fmt.Errorf("invalid %s=%q: %s", ConfigEnvName(key), val, err.Error())
+		}
+		cfg.sources[key] = CONFIG_SOURCE_ENV
+	}
+
+	return nil
+}
+
+func isEnvSupported(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return isEnvSupported(typ.Elem())
+	}
+
+	return false
+}
+
+// set string value to field, lists are separated by ','
+func setValueFromString(v reflect.Value, s string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(i)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		items := make([]string, 0)
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+
+		list := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setValueFromString(list.Index(i), item); err != nil {
+				return err
+			}
+		}
+		v.Set(list)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+// check whether nested key exists, keys are case-insensitive like unmarshalling
+func hasKeyFold(m map[string]interface{}, path []string) bool {
+	var v interface{} = m
+	for _, p := range path {
+		table, ok := v.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if v, ok = getKeyFold(table, p); !ok {
+			return false
+		}
+	}
+
+	return true
+}
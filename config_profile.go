@@ -0,0 +1,107 @@
+// Copyright 2019 - now The https://github.com/nvwa-io/wago Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wago
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Profiles are layered over base config file in order, e.g: base config file is config/app.toml
+// 1. config/app.toml
+// 2. config/app.<RunMode>.toml, e.g: config/app.release.toml
+// 3. config/app.local.toml, for local development, shouldn't be committed
+// tables are deep merged, while arrays and other values are replaced.
+// RunMode is read from WAGO_APP_RUNMODE or base config file.
+const (
+	PROFILE_LOCAL = "local"
+)
+
+// Files returns loaded config files, in merging order
+func (t *Config) Files() []string {
+	return append([]string{}, t.files...)
+}
+
+// profile files layered over base config file
+func profileFiles(path, runMode string) []string {
+	ext := filepath.Ext(path)
+	name := strings.TrimSuffix(path, ext)
+
+	files := make([]string, 0, 2)
+	if runMode != "" {
+		files = append(files, name+"."+runMode+ext)
+	}
+	files = append(files, name+"."+PROFILE_LOCAL+ext)
+
+	return files
+}
+
+// RunMode to choose profile, environment variable takes precedence
+func profileRunMode(base map[string]interface{}) string {
+	if v, ok := os.LookupEnv(ConfigEnvName("App.RunMode")); ok {
+		return v
+	}
+
+	app, _ := getKeyFold(base, "App")
+	table, ok := app.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	v, _ := getKeyFold(table, "RunMode")
+	runMode, _ := v.(string)
+
+	return runMode
+}
+
+// deep merge src into dst, tables are merged, arrays and other values are replaced.
+// keys are case-insensitive like unmarshalling.
+func mergeConfigMap(dst, src map[string]interface{}) {
+	for k, v := range src {
+		dk := k
+		for key := range dst {
+			if strings.EqualFold(key, k) {
+				dk = key
+				break
+			}
+		}
+
+		srcTable, ok1 := v.(map[string]interface{})
+		dstTable, ok2 := dst[dk].(map[string]interface{})
+		if ok1 && ok2 {
+			mergeConfigMap(dstTable, srcTable)
+			continue
+		}
+
+		delete(dst, dk)
+		if ok1 {
+			// copy table to avoid sharing maps between layers
+			table := make(map[string]interface{}, len(srcTable))
+			mergeConfigMap(table, srcTable)
+			v = table
+		}
+		dst[k] = v
+	}
+}
+
+// get value of key, case-insensitive
+func getKeyFold(m map[string]interface{}, key string) (interface{}, bool) {
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+
+	return nil, false
+}
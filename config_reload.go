@@ -0,0 +1,177 @@
+// Copyright 2019 - now The https://github.com/nvwa-io/wago Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wago
+
+import (
+	"context"
+	"errors"
+	"github.com/nvwa-io/wago/logger"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"time"
+)
+
+// Config is reloaded while config files changed (App.WatchConfig = true) or receiving SIGHUP.
+// new config is validated before applied, and settings below take effect without restart:
+// Log.Formatter, Log.Level, Log.LogMethodName, Server.Cors.
+// other settings, e.g: Server.Port, take effect after restart.
+
+// ConfigChangeHandler is invoked after config is replaced
+type ConfigChangeHandler func(old, new *Config)
+
+// OnConfigChange registers a handler invoked after default app's config is replaced
+func OnConfigChange(h ConfigChangeHandler) {
+	WagoApp.OnConfigChange(h)
+}
+
+// Reload reloads default app's config from config files
+func Reload() error {
+	return WagoApp.Reload()
+}
+
+// OnConfigChange registers a handler invoked after current app's config is replaced
+func (t *Wago) OnConfigChange(h ConfigChangeHandler) {
+	t.configChangeHandlers = append(t.configChangeHandlers, h)
+}
+
+// Reload reloads current app's config from config files,
+// old config is kept while new config is invalid.
+func (t *Wago) Reload() error {
+	path := t.Config().path
+	if path == "" {
+		return errors.New("wago: config isn't loaded from file, can't be reloaded")
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return err
+	}
+
+	return t.swapConfig(cfg)
+}
+
+// replace config, re-fill bound structs, apply live settings and notify subscribers
+func (t *Wago) swapConfig(cfg *Config) error {
+	values := make([]reflect.Value, len(t.bindings))
+	errs := make(ConfigErrors, 0)
+	for i, b := range t.bindings {
+		v, err := cfg.decodeSection(b.section, b.v)
+		if err != nil {
+			if list, ok := err.(ConfigErrors); ok {
+				errs = append(errs, list...)
+				continue
+			}
+			return err
+		}
+		values[i] = v
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	t.mu.Lock()
+	old := t.config
+	if cfg.Server.Cors.AllowOriginFunc == nil {
+		cfg.Server.Cors.AllowOriginFunc = old.Server.Cors.AllowOriginFunc
+	}
+	t.config = cfg
+	for i, b := range t.bindings {
+		reflect.ValueOf(b.v).Elem().Set(values[i].Elem())
+	}
+	t.mu.Unlock()
+
+	// apply live settings while app is configured
+	if t.cors.Load() != nil {
+		configLogger(cfg.Log)
+		t.cors.Store(newCorsHandler(cfg.Server.Cors))
+	}
+
+	for _, h := range t.configChangeHandlers {
+		h(old, cfg)
+	}
+
+	return nil
+}
+
+// watch config files and SIGHUP until ctx is done,
+// TLS certificate is reloaded while receiving SIGHUP too.
+func (t *Wago) watch(ctx context.Context, reloader *certReloader) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	cfg := t.Config()
+	var tick <-chan time.Time
+	if cfg.App.WatchConfig && cfg.path != "" {
+		ticker := time.NewTicker(time.Duration(cfg.App.WatchInterval) * time.Second)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	stamps := configFileStamps(cfg)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+			current := configFileStamps(t.Config())
+			if reflect.DeepEqual(stamps, current) {
+				continue
+			}
+			stamps = current
+			t.reloadAndLog()
+		case <-hup:
+			t.reloadAndLog()
+			if reloader == nil {
+				continue
+			}
+			if err := reloader.reload(); err != nil {
+				logger.Errorf("failed to reload TLS certificate, err=%s", err.Error())
+				continue
+			}
+			logger.Infof("TLS certificate %s reloaded", reloader.certFile)
+		}
+	}
+}
+
+func (t *Wago) reloadAndLog() {
+	if t.Config().path == "" {
+		return
+	}
+	if err := t.Reload(); err != nil {
+		logger.Errorf("failed to reload config, keep current config, err=%s", err.Error())
+		return
+	}
+	logger.Infof("config %s reloaded", t.Config().path)
+}
+
+// modification time of base config file and all possible profiles,
+// so that profiles created after boot are detected too.
+func configFileStamps(cfg *Config) map[string]time.Time {
+	stamps := make(map[string]time.Time)
+	if cfg.path == "" {
+		return stamps
+	}
+
+	files := append([]string{cfg.path}, profileFiles(cfg.path, cfg.App.RunMode)...)
+	for _, f := range files {
+		if fi, err := os.Stat(f); err == nil {
+			stamps[f] = fi.ModTime()
+		}
+	}
+
+	return stamps
+}
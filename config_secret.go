@@ -0,0 +1,267 @@
+// Copyright 2019 - now The https://github.com/nvwa-io/wago Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wago
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/nvwa-io/wago/logger"
+	"github.com/nvwa-io/wago/util/secret"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// String values in config files are interpolated before unmarshalling, e.g:
+// password = "${DB_PASSWORD}"                    => value of environment variable DB_PASSWORD
+// host     = "${DB_HOST:-127.0.0.1}"             => use default value while DB_HOST is not set
+// password = "file:///run/secrets/db_password"   => content of file, trailing newline is trimmed
+// password = "enc:v1:..."                         => decrypted value, refer to util/secret
+// values from environment variables, files or decrypted are treated as secrets, and redacted in Config.Dump(), Config.String() and logs.
+const (
+	SECRET_FILE_PREFIX = "file://"
+	SECRET_REDACTED    = "******"
+
+	// secret values shorter than it aren't redacted from logs, to avoid mangling messages
+	secretMinLogLength = 4
+)
+
+var (
+	envRefRegex = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+)
+
+// IsSecret checks whether value of key is interpolated from environment variables, files or decrypted, key is case-insensitive,
+// e.g: database.password, or server.listeners[0].address for items of arrays
+func (t *Config) IsSecret(key string) bool {
+	return t.secrets[strings.ToLower(key)]
+}
+
+// Dump returns config content with secret values redacted
+func (t *Config) Dump() map[string]interface{} {
+	m := t.Tree.ToMap()
+	t.redactMap(m, "")
+
+	return m
+}
+
+// String returns config content as JSON with secret values redacted
+func (t *Config) String() string {
+	data, err := json.Marshal(t.Dump())
+	if err != nil {
+		return fmt.Sprintf("wago: failed to dump config, err=%s", err.Error())
+	}
+
+	return string(data)
+}
+
+// Redact replaces secret values in s
+func (t *Config) Redact(s string) string {
+	for _, v := range t.secretValues {
+		s = strings.Replace(s, v, SECRET_REDACTED, -1)
+	}
+
+	return s
+}
+
+func (t *Config) redactMap(m map[string]interface{}, path string) {
+	for k, v := range m {
+		key := strings.ToLower(strings.TrimPrefix(path+"."+k, "."))
+		if t.secrets[key] {
+			m[k] = SECRET_REDACTED
+			continue
+		}
+		m[k] = t.redactValue(v, key)
+	}
+}
+
+// redact tables and arrays, arrays are copied since ToMap doesn't copy them
+func (t *Config) redactValue(v interface{}, key string) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		t.redactMap(val, key)
+	case []interface{}:
+		list := make([]interface{}, len(val))
+		for i, item := range val {
+			itemKey := fmt.Sprintf("%s[%d]", key, i)
+			if t.secrets[itemKey] {
+				list[i] = SECRET_REDACTED
+				continue
+			}
+			if table, ok := item.(map[string]interface{}); ok {
+				copied := make(map[string]interface{}, len(table))
+				mergeConfigMap(copied, table)
+				item = copied
+			}
+			list[i] = t.redactValue(item, itemKey)
+		}
+		return list
+	}
+
+	return v
+}
+
+// secretResolver interpolates string values of config content,
+// and records secrets into cfg.
+type secretResolver struct {
+	cfg  *Config
+	errs ConfigErrors
+
+	// config key is loaded only while any encrypted value exists
+	key    []byte
+	keyErr error
+}
+
+// interpolate string values of config content in place
+func (t *secretResolver) resolve(m map[string]interface{}, path string) {
+	for k, v := range m {
+		key := strings.TrimPrefix(path+"."+k, ".")
+		switch val := v.(type) {
+		case map[string]interface{}:
+			t.resolve(val, key)
+		case string:
+			if s, ok := t.resolveValue(key, val); ok {
+				m[k] = s
+			}
+		case []interface{}:
+			t.resolveList(val, key)
+		}
+	}
+}
+
+// interpolate items of array in place, tables in array are keyed by index, e.g: server.listeners[0].address
+func (t *secretResolver) resolveList(list []interface{}, path string) {
+	for i, item := range list {
+		key := fmt.Sprintf("%s[%d]", path, i)
+		switch val := item.(type) {
+		case map[string]interface{}:
+			t.resolve(val, key)
+		case string:
+			if s, ok := t.resolveValue(key, val); ok {
+				list[i] = s
+			}
+		case []interface{}:
+			t.resolveList(val, key)
+		}
+	}
+}
+
+// interpolate single value, returns false while nothing is changed.
+// values from environment variables, files or decrypted are secrets, default values aren't.
+func (t *secretResolver) resolveValue(key, s string) (string, bool) {
+	changed, sensitive := false, false
+	s = envRefRegex.ReplaceAllStringFunc(s, func(ref string) string {
+		matches := envRefRegex.FindStringSubmatch(ref)
+		changed = true
+		if v, ok := os.LookupEnv(matches[1]); ok {
+			sensitive = true
+			return v
+		}
+		if matches[2] != "" {
+			return matches[3]
+		}
+		t.addErr(key, "environment variable %s is not set", matches[1])
+		return ""
+	})
+
+	if strings.HasPrefix(s, SECRET_FILE_PREFIX) {
+		data, err := ioutil.ReadFile(strings.TrimPrefix(s, SECRET_FILE_PREFIX))
+		if err != nil {
+			t.addErr(key, "failed to read secret file: %s", err.Error())
+			return s, false
+		}
+		s = strings.TrimRight(string(data), "\r\n")
+		changed, sensitive = true, true
+	}
+
+	if secret.IsEncrypted(s) {
+		if t.key == nil && t.keyErr == nil {
+			t.key, t.keyErr = secret.LoadKey()
+		}
+		if t.keyErr != nil {
+			t.addErr(key, "%s", t.keyErr.Error())
+			return s, false
+		}
+
+		plaintext, err := secret.Decrypt(t.key, s)
+		if err != nil {
+			t.addErr(key, "%s", err.Error())
+			return s, false
+		}
+		s = plaintext
+		changed, sensitive = true, true
+	}
+
+	if sensitive {
+		t.cfg.addSecret(key, s)
+	}
+
+	return s, changed
+}
+
+func (t *secretResolver) addErr(key, format string, args ...interface{}) {
+	t.errs = append(t.errs, &ConfigFieldError{Key: key, Message: fmt.Sprintf(format, args...)})
+}
+
+func (t *Config) addSecret(key, value string) {
+	if t.secrets == nil {
+		t.secrets = make(map[string]bool)
+	}
+	t.secrets[strings.ToLower(key)] = true
+
+	if len(value) < secretMinLogLength {
+		return
+	}
+	for _, v := range t.secretValues {
+		if v == value {
+			return
+		}
+	}
+	t.secretValues = append(t.secretValues, value)
+
+	// replace longer values first, in case one secret contains another
+	sort.Slice(t.secretValues, func(i, j int) bool {
+		return len(t.secretValues[i]) > len(t.secretValues[j])
+	})
+}
+
+// secretRedactHook redacts secret values of app's config from log entries
+type secretRedactHook struct {
+	app *Wago
+}
+
+func (t *secretRedactHook) Levels() []logger.Level {
+	return logrus.AllLevels
+}
+
+func (t *secretRedactHook) Fire(entry *logger.Entry) error {
+	cfg := t.app.Config()
+	if len(cfg.secretValues) == 0 {
+		return nil
+	}
+
+	entry.Message = cfg.Redact(entry.Message)
+	for k, v := range entry.Data {
+		switch val := v.(type) {
+		case string:
+			entry.Data[k] = cfg.Redact(val)
+		case error:
+			entry.Data[k] = cfg.Redact(val.Error())
+		}
+	}
+
+	return nil
+}
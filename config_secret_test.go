@@ -0,0 +1,56 @@
+// Copyright 2019 - now The https://github.com/nvwa-io/wago Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wago
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveArrayTables(t *testing.T) {
+	t.Setenv("WAGO_TEST_ADDR", "127.0.0.1:18080")
+	cfg, err := LoadConfigFromReader(strings.NewReader(`
+[server]
+[[server.listeners]]
+network = "tcp"
+address = "${WAGO_TEST_ADDR}"
+
+[[server.listeners]]
+network = "unix"
+address = "${WAGO_TEST_SOCK:-/tmp/wago.sock}"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list := cfg.Server.Listeners
+	if len(list) != 2 || list[0].Address != "127.0.0.1:18080" || list[1].Address != "/tmp/wago.sock" {
+		t.Fatalf("unexpected listeners: %+v", list)
+	}
+	if !cfg.IsSecret("server.listeners[0].address") || cfg.IsSecret("server.listeners[1].address") {
+		t.Fatalf("unexpected secrets: %v", cfg.secrets)
+	}
+
+	// secrets in array tables are redacted from dump, but not from config tree
+	if s := cfg.String(); strings.Contains(s, "127.0.0.1:18080") || !strings.Contains(s, "/tmp/wago.sock") {
+		t.Fatalf("unexpected dump: %s", s)
+	}
+	if s := cfg.String(); strings.Contains(s, "127.0.0.1:18080") {
+		t.Fatalf("unexpected second dump: %s", s)
+	}
+	tables, _ := cfg.Tree.Get("server.listeners").([]interface{})
+	if len(tables) != 2 || tables[0].(map[string]interface{})["address"] != "127.0.0.1:18080" {
+		t.Fatalf("config tree is changed by dump: %v", tables)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2019 - now The https://github.com/nvwa-io/wago Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wago
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ConfigTree is the format-neutral content of merged configuration,
+// custom sections can be read from it no matter which format config file is.
+// keys are dot separated and case-insensitive, e.g: "payment.gateway.timeout"
+type ConfigTree struct {
+	m map[string]interface{}
+}
+
+func newConfigTree(m map[string]interface{}) *ConfigTree {
+	if m == nil {
+		m = make(map[string]interface{})
+	}
+
+	return &ConfigTree{m: m}
+}
+
+// Get returns value of key, nil while key doesn't exist.
+// tables are returned as map[string]interface{}
+func (t *ConfigTree) Get(key string) interface{} {
+	v, _ := t.lookup(key)
+	return v
+}
+
+// Has checks whether key exists
+func (t *ConfigTree) Has(key string) bool {
+	_, ok := t.lookup(key)
+	return ok
+}
+
+// GetString returns value of key as string, "" while key doesn't exist
+func (t *ConfigTree) GetString(key string) string {
+	v, ok := t.lookup(key)
+	if !ok || v == nil {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+
+	return fmt.Sprint(v)
+}
+
+// Sub returns sub tree of table key, nil while key doesn't exist or isn't a table
+func (t *ConfigTree) Sub(key string) *ConfigTree {
+	v, _ := t.lookup(key)
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	return newConfigTree(m)
+}
+
+// Keys returns sorted top level keys
+func (t *ConfigTree) Keys() []string {
+	keys := make([]string, 0, len(t.m))
+	for k := range t.m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// ToMap returns a deep copy of tree content
+func (t *ConfigTree) ToMap() map[string]interface{} {
+	m := make(map[string]interface{}, len(t.m))
+	mergeConfigMap(m, t.m)
+
+	return m
+}
+
+// Unmarshal decodes tree content into v, field names are case-insensitive
+func (t *ConfigTree) Unmarshal(v interface{}) error {
+	return decodeConfigMap(t.m, v)
+}
+
+func (t *ConfigTree) lookup(key string) (interface{}, bool) {
+	var v interface{} = t.m
+	for _, k := range strings.Split(key, ".") {
+		table, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if v, ok = getKeyFold(table, k); !ok {
+			return nil, false
+		}
+	}
+
+	return v, true
+}
+
+// decode config content into struct, JSON is used as the neutral intermediate format
+func decodeConfigMap(m map[string]interface{}, v interface{}) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// normalize parsed content, e.g: YAML maps with non-string keys
+func normalizeConfigValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = normalizeConfigValue(item)
+		}
+		return val
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			m[fmt.Sprint(k)] = normalizeConfigValue(item)
+		}
+		return m
+	case []interface{}:
+		for i, item := range val {
+			val[i] = normalizeConfigValue(item)
+		}
+		return val
+	case []map[string]interface{}:
+		list := make([]interface{}, len(val))
+		for i, item := range val {
+			list[i] = normalizeConfigValue(item)
+		}
+		return list
+	}
+
+	return v
+}
@@ -0,0 +1,143 @@
+// Copyright 2019 - now The https://github.com/nvwa-io/wago Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wago
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Validate checks configuration, all problems are reported as ConfigErrors with key path,
+// e.g: App.RouterMode: invalid value "coment", must be one of ["auto" "comment"]
+func (t *Config) Validate() error {
+	errs := make(ConfigErrors, 0)
+	addErr := func(key, format string, args ...interface{}) {
+		errs = append(errs, &ConfigFieldError{Key: key, Message: fmt.Sprintf(format, args...)})
+	}
+	oneOf := func(key, value string, allowed ...string) {
+		for _, v := range allowed {
+			if value == v {
+				return
+			}
+		}
+		addErr(key, "invalid value %q, must be one of %q", value, allowed)
+	}
+	nonNegative := func(key string, value int) {
+		if value < 0 {
+			addErr(key, "invalid value %d, must not be negative", value)
+		}
+	}
+
+	// [app]
+	oneOf("App.RunMode", t.App.RunMode, RUN_MODE_DEBUG, RUN_MODE_TEST, RUN_MODE_RELEASE)
+	oneOf("App.RouterMode", t.App.RouterMode, ROUTER_MODE_AUTO, ROUTER_MODE_COMMENT)
+	if st := t.App.Response.FailStatus; st < 100 || st > 599 {
+		addErr("App.Response.FailStatus", "invalid value %d, must be in [100, 599]", st)
+	}
+	oneOf("App.RouterSep", t.App.RouterSep, "", "-", "_")
+	if t.App.ControllerPath == "" {
+		addErr("App.ControllerPath", "must not be empty")
+	}
+	nonNegative("App.WatchInterval", t.App.WatchInterval)
+
+	// [log]
+	oneOf("Log.Formatter", strings.ToLower(t.Log.Formatter), "json", "text", "logstash", "fluentd")
+	if t.Log.Level > uint32(LevelTrace) {
+		addErr("Log.Level", "invalid value %d, must be in [%d, %d]", t.Log.Level, LevelPanic, LevelTrace)
+	}
+	nonNegative("Log.MaxSize", t.Log.MaxSize)
+	nonNegative("Log.MaxBackups", t.Log.MaxBackups)
+	nonNegative("Log.MaxAge", t.Log.MaxAge)
+
+	// [server]
+	s := t.Server
+	oneOf("Server.Mode", s.Mode, SERVER_MODE_HTTP, SERVER_MODE_FCGI, SERVER_MODE_CGI)
+	if s.Mode == SERVER_MODE_CGI && s.Prefork > 0 {
+		addErr("Server.Prefork", "must be 0 while Server.Mode is cgi")
+	}
+	if s.Port <= 0 || s.Port > 65535 {
+		addErr("Server.Port", "invalid value %d, must be in [1, 65535]", s.Port)
+	}
+	nonNegative("Server.ReadTimeout", s.ReadTimeout)
+	nonNegative("Server.ReadHeaderTimeout", s.ReadHeaderTimeout)
+	nonNegative("Server.WriteTimeout", s.WriteTimeout)
+	nonNegative("Server.IdleTimeout", s.IdleTimeout)
+	nonNegative("Server.MaxHeaderBytes", s.MaxHeaderBytes)
+	nonNegative("Server.ShutdownTimeout", s.ShutdownTimeout)
+	nonNegative("Server.Prefork", s.Prefork)
+	if s.Prefork > 0 && !preforkSupported {
+		addErr("Server.Prefork", "prefork isn't supported on current platform")
+	}
+
+	// [server.cors]
+	for _, origin := range s.Cors.AllowOrigins {
+		if origin != "*" && !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://") {
+			addErr("Server.Cors.AllowOrigins", "invalid origin %q, must be '*' or start with http:// or https://", origin)
+		}
+	}
+	nonNegative("Server.Cors.MaxAge", s.Cors.MaxAge)
+
+	// [[server.listeners]]
+	for i, l := range s.Listeners {
+		key := fmt.Sprintf("Server.Listeners[%d]", i)
+		oneOf(key+".Network", l.Network, NETWORK_TCP, NETWORK_TLS, NETWORK_UNIX, NETWORK_SYSTEMD)
+		if l.Address == "" && l.Network != NETWORK_SYSTEMD {
+			addErr(key+".Address", "must not be empty")
+		}
+		if _, err := parsePerm(l.Perm); err != nil {
+			addErr(key+".Perm", "invalid value %q, must be octal file mode, e.g: 0660", l.Perm)
+		}
+		if s.Mode == SERVER_MODE_FCGI && (l.Network == NETWORK_TLS || l.TLS) {
+			addErr(key+".Network", "TLS isn't supported while Server.Mode is fcgi")
+		}
+		if s.Prefork > 0 && l.Network != NETWORK_TCP && l.Network != NETWORK_TLS {
+			addErr(key+".Network", "only tcp and tls listeners can be shared by prefork workers")
+		}
+	}
+
+	if s.Mode == SERVER_MODE_FCGI && len(s.Listeners) == 0 && s.TLS.Enable {
+		addErr("Server.TLS.Enable", "TLS isn't supported while Server.Mode is fcgi")
+	}
+
+	// [server.http2]
+	if n := s.HTTP2.MaxReadFrameSize; n != 0 && (n < HTTP2_MIN_FRAME_SIZE || n > HTTP2_MAX_FRAME_SIZE) {
+		addErr("Server.HTTP2.MaxReadFrameSize", "invalid value %d, must be in [%d, %d]", n, HTTP2_MIN_FRAME_SIZE, HTTP2_MAX_FRAME_SIZE)
+	}
+	nonNegative("Server.HTTP2.IdleTimeout", s.HTTP2.IdleTimeout)
+
+	// [server.tls], development certificate is generated while RunMode is debug
+	if s.Mode != SERVER_MODE_CGI && s.usesTLS() && !t.usesDevCert() {
+		if s.TLS.CertFile == "" {
+			addErr("Server.TLS.CertFile", "must not be empty while TLS is enabled")
+		}
+		if s.TLS.KeyFile == "" {
+			addErr("Server.TLS.KeyFile", "must not be empty while TLS is enabled")
+		}
+	}
+	if _, ok := tlsVersions[s.TLS.MinVersion]; !ok && s.TLS.MinVersion != "" {
+		addErr("Server.TLS.MinVersion", "invalid value %q, must be one of [1.0 1.1 1.2 1.3]", s.TLS.MinVersion)
+	}
+	if _, err := parseCipherSuites(s.TLS.CipherSuites); err != nil {
+		addErr("Server.TLS.CipherSuites", "%s", err.Error())
+	}
+	if _, ok := tlsClientAuth[strings.ToLower(s.TLS.ClientAuth)]; !ok && s.TLS.ClientAuth != "" {
+		addErr("Server.TLS.ClientAuth", "invalid value %q, must be one of [request require verify_if_given require_and_verify]", s.TLS.ClientAuth)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
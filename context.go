@@ -17,6 +17,21 @@ import "github.com/gin-gonic/gin"
 
 const (
 	REQUEST_ID = "W-Request-Id"
+
+	// controller and method name of current request, set by HandlerWrapper
+	CONTROLLER_NAME = "W-Controller"
+	METHOD_NAME     = "W-Method"
+
+	// app serving current request
+	APP = "W-App"
 )
 
 type Context = gin.Context
+
+// AppFromContext returns app serving current request, default app is returned while not found
+func AppFromContext(c *Context) *Wago {
+	if app, ok := c.Value(APP).(*Wago); ok {
+		return app
+	}
+	return WagoApp
+}
@@ -37,3 +37,8 @@ func (t *Controller) Init(c *Context) {
 func (t *Controller) RequestId() string {
 	return t.Ctx.GetString(REQUEST_ID)
 }
+
+// validate struct by validate tags, refer to validate.go
+func (t *Controller) Validate(v interface{}) error {
+	return Validate(v)
+}
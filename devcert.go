@@ -0,0 +1,198 @@
+// Copyright 2019 - now The https://github.com/nvwa-io/wago Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wago
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"github.com/nvwa-io/wago/logger"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Development certificate, used while RunMode is debug, TLS is enabled and CertFile/KeyFile aren't set.
+// a local CA and a certificate of localhost signed by it are generated into DEV_CERT_DIR,
+// and reused until expired. trust the CA once to get rid of browser warnings.
+const (
+	DEV_CERT_DIR = ".wago/certs"
+
+	devCAFile      = "ca.pem"
+	devCAKeyFile   = "ca-key.pem"
+	devCertFile    = "localhost.pem"
+	devCertKeyFile = "localhost-key.pem"
+
+	// browsers reject certificates valid for more than 825 days
+	devCertDays = 825
+	devCADays   = 10 * 365
+)
+
+// whether development certificate is used instead of [server.tls] cert files
+func (t *Config) usesDevCert() bool {
+	return t.App.RunMode == RUN_MODE_DEBUG && t.Server.TLS.CertFile == "" && t.Server.TLS.KeyFile == ""
+}
+
+// [server.tls] configuration with development certificate while needed
+func (t *Config) tlsConfig() (TLS, error) {
+	c := t.Server.TLS
+	if !t.usesDevCert() {
+		return c, nil
+	}
+
+	certFile, keyFile, err := devCert(DEV_CERT_DIR)
+	if err != nil {
+		return c, fmt.Errorf("failed to generate development certificate, err=%s", err.Error())
+	}
+	c.CertFile, c.KeyFile = certFile, keyFile
+
+	return c, nil
+}
+
+// generate development certificate into dir while not exists or expired
+func devCert(dir string) (certFile, keyFile string, err error) {
+	caFile, caKeyFile := filepath.Join(dir, devCAFile), filepath.Join(dir, devCAKeyFile)
+	certFile, keyFile = filepath.Join(dir, devCertFile), filepath.Join(dir, devCertKeyFile)
+
+	if pair, err := tls.LoadX509KeyPair(certFile, keyFile); err == nil {
+		if leaf, err := x509.ParseCertificate(pair.Certificate[0]); err == nil && time.Now().Before(leaf.NotAfter) {
+			return certFile, keyFile, nil
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", "", err
+	}
+
+	ca, caKey, err := loadDevCA(caFile, caKeyFile)
+	if err != nil {
+		if ca, caKey, err = newDevCA(caFile, caKeyFile); err != nil {
+			return "", "", err
+		}
+		printDevCATrust(caFile)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: newSerialNumber(),
+		Subject:      pkix.Name{Organization: []string{"wago development certificate"}, CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(0, 0, devCertDays),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return "", "", err
+	}
+	if err := writePEM(certFile, keyFile, der, key); err != nil {
+		return "", "", err
+	}
+	logger.Infof("development certificate %s generated", certFile)
+
+	return certFile, keyFile, nil
+}
+
+// load local CA, error is returned while not exists or expired
+func loadDevCA(caFile, caKeyFile string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	pair, err := tls.LoadX509KeyPair(caFile, caKeyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	ca, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	key, ok := pair.PrivateKey.(*ecdsa.PrivateKey)
+	if !ok || !ca.IsCA || time.Now().After(ca.NotAfter) {
+		return nil, nil, fmt.Errorf("invalid development CA %s", caFile)
+	}
+
+	return ca, key, nil
+}
+
+func newDevCA(caFile, caKeyFile string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hostname, _ := os.Hostname()
+	tmpl := &x509.Certificate{
+		SerialNumber:          newSerialNumber(),
+		Subject:               pkix.Name{Organization: []string{"wago development CA"}, CommonName: "wago development CA " + hostname},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(0, 0, devCADays),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := writePEM(caFile, caKeyFile, der, key); err != nil {
+		return nil, nil, err
+	}
+	ca, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ca, key, nil
+}
+
+// write certificate and private key as PEM, private key is only readable by owner
+func writePEM(certFile, keyFile string, der []byte, key *ecdsa.PrivateKey) error {
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+}
+
+func newSerialNumber() *big.Int {
+	n, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	return n
+}
+
+// print how to trust local CA
+func printDevCATrust(caFile string) {
+	path, _ := filepath.Abs(caFile)
+	logger.Warnf("development CA %s generated, trust it to avoid browser warnings:\n"+
+		"  macOS:   sudo security add-trusted-cert -d -r trustRoot -k /Library/Keychains/System.keychain %s\n"+
+		"  Linux:   sudo cp %s /usr/local/share/ca-certificates/wago-dev-ca.crt && sudo update-ca-certificates\n"+
+		"  Windows: certutil -addstore -f ROOT %s\n"+
+		"  Firefox: Settings > Privacy & Security > Certificates > View Certificates > Authorities > Import\n"+
+		"never trust it on production machines, and keep %s private.",
+		path, path, path, path, filepath.Join(filepath.Dir(path), devCAKeyFile))
+}
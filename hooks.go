@@ -0,0 +1,127 @@
+// Copyright 2019 - now The https://github.com/nvwa-io/wago Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wago
+
+import (
+	"context"
+	"fmt"
+	"github.com/nvwa-io/wago/logger"
+)
+
+// Lifecycle of wago app, hooks of each stage are run in registration order:
+// OnBoot: after config is validated and logger is configured, before routers are registered,
+// error aborts startup.
+// BeforeServe: after routers are registered and listener is bound, before serving requests,
+// error aborts startup.
+// once OnBoot hooks have run, OnShutdown hooks are still run to release resources while startup is aborted,
+// e.g: by BeforeServe error, TLS error or failing to bind listeners.
+// AfterServe: after HTTP server stopped and in-flight requests are drained.
+// OnShutdown: at last, e.g: to close DB connections.
+// errors of AfterServe and OnShutdown hooks are logged.
+const (
+	HOOK_BOOT         = "OnBoot"
+	HOOK_BEFORE_SERVE = "BeforeServe"
+	HOOK_AFTER_SERVE  = "AfterServe"
+	HOOK_SHUTDOWN     = "OnShutdown"
+)
+
+// Hook is invoked at a stage of wago app's lifecycle
+type Hook func(ctx context.Context) error
+
+// ShutdownHook is invoked while wago app is shutting down,
+// after in-flight requests have been drained.
+type ShutdownHook = Hook
+
+// HookError is returned while a hook aborts startup
+type HookError struct {
+	// lifecycle stage, e.g: OnBoot
+	Stage string
+	Err   error
+}
+
+func (t *HookError) Error() string {
+	return fmt.Sprintf("wago: %s hook failed, err=%s", t.Stage, t.Err.Error())
+}
+
+func (t *HookError) Unwrap() error {
+	return t.Err
+}
+
+// OnBoot registers a hook run after config is loaded, before routers are registered
+func OnBoot(hook Hook) {
+	WagoApp.OnBoot(hook)
+}
+
+// BeforeServe registers a hook run after listener is bound, before serving requests
+func BeforeServe(hook Hook) {
+	WagoApp.BeforeServe(hook)
+}
+
+// AfterServe registers a hook run after HTTP server stopped
+func AfterServe(hook Hook) {
+	WagoApp.AfterServe(hook)
+}
+
+// OnShutdown registers a hook to be run while wago app is shutting down.
+// hooks are run in registration order after in-flight requests are drained.
+func OnShutdown(hook Hook) {
+	WagoApp.OnShutdown(hook)
+}
+
+// OnBoot registers a hook run after config is loaded, before routers are registered
+func (t *Wago) OnBoot(hook Hook) {
+	t.addHook(HOOK_BOOT, hook)
+}
+
+// BeforeServe registers a hook run after listener is bound, before serving requests
+func (t *Wago) BeforeServe(hook Hook) {
+	t.addHook(HOOK_BEFORE_SERVE, hook)
+}
+
+// AfterServe registers a hook run after HTTP server stopped
+func (t *Wago) AfterServe(hook Hook) {
+	t.addHook(HOOK_AFTER_SERVE, hook)
+}
+
+// OnShutdown registers a hook to be run while current app is shutting down.
+func (t *Wago) OnShutdown(hook Hook) {
+	t.addHook(HOOK_SHUTDOWN, hook)
+}
+
+func (t *Wago) addHook(stage string, hook Hook) {
+	if t.hooks == nil {
+		t.hooks = make(map[string][]Hook)
+	}
+	t.hooks[stage] = append(t.hooks[stage], hook)
+}
+
+// run hooks of stage in order, stop at first error
+func (t *Wago) runHooks(ctx context.Context, stage string) error {
+	for _, hook := range t.hooks[stage] {
+		if err := hook(ctx); err != nil {
+			return &HookError{Stage: stage, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// run all hooks of stage, errors are logged
+func (t *Wago) runHooksAndLog(ctx context.Context, stage string) {
+	for _, hook := range t.hooks[stage] {
+		if err := hook(ctx); err != nil {
+			logger.Errorf("%s hook failed, err=%s", stage, err.Error())
+		}
+	}
+}
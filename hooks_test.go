@@ -0,0 +1,50 @@
+// Copyright 2019 - now The https://github.com/nvwa-io/wago Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wago
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func TestShutdownHooksRunWhileBindFails(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	cfg := DefaultConfig()
+	cfg.Log.Filename = t.TempDir() + "/wago.log"
+	cfg.Server.Listeners = []Listener{{Network: NETWORK_TCP, Address: l.Addr().String()}}
+
+	app := New(cfg)
+	booted, shutdown := 0, 0
+	app.OnBoot(func(ctx context.Context) error {
+		booted++
+		return nil
+	})
+	app.OnShutdown(func(ctx context.Context) error {
+		shutdown++
+		return nil
+	})
+
+	if err := app.Run(context.Background()); err == nil {
+		t.Fatal("expected bind error")
+	}
+	if booted != 1 || shutdown != 1 {
+		t.Fatalf("booted = %d, shutdown = %d", booted, shutdown)
+	}
+}
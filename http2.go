@@ -0,0 +1,49 @@
+// Copyright 2019 - now The https://github.com/nvwa-io/wago Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wago
+
+import (
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"net/http"
+	"time"
+)
+
+// frame size range of HTTP/2, refer to RFC 7540 section 4.2
+const (
+	HTTP2_MIN_FRAME_SIZE = 1 << 14
+	HTTP2_MAX_FRAME_SIZE = 1<<24 - 1
+)
+
+// config HTTP/2 of server by [server.http2], call it after server.TLSConfig is set
+func configHTTP2(server *http.Server, c HTTP2) error {
+	h2 := &http2.Server{
+		MaxConcurrentStreams: c.MaxConcurrentStreams,
+		MaxReadFrameSize:     c.MaxReadFrameSize,
+		IdleTimeout:          time.Duration(c.IdleTimeout) * time.Second,
+	}
+
+	if server.TLSConfig != nil {
+		if err := http2.ConfigureServer(server, h2); err != nil {
+			return err
+		}
+	}
+
+	// connections with HTTP/2 preface or "Upgrade: h2c" are served by h2, others by HTTP/1.x
+	if c.H2C {
+		server.Handler = h2c.NewHandler(server.Handler, h2)
+	}
+
+	return nil
+}
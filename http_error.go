@@ -0,0 +1,180 @@
+// Copyright 2019 - now The https://github.com/nvwa-io/wago Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wago
+
+import (
+	"errors"
+	"fmt"
+	"github.com/nvwa-io/wago/logger"
+	"net/http"
+)
+
+// Controller methods may return error or (T, error), e.g:
+// func (t *UserController) Info() (*User, error)
+// T is rendered by app's Responder, JSON 200 by default.
+// error is rendered by app's ErrorRenderer, *HTTPError is responded with its status, others with 500.
+type (
+	// ErrorRenderer renders error returned by controller method
+	ErrorRenderer func(c *Context, err error)
+
+	// Responder renders data returned by controller method
+	Responder func(c *Context, data interface{})
+)
+
+// HTTPError is an error responded to client with status, code, message and details
+type HTTPError struct {
+	// HTTP status code
+	Status int `json:"-"`
+
+	// business code, default is Status
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+
+	// cause of error, logged but not responded
+	Err error `json:"-"`
+}
+
+// NewHTTPError creates HTTPError, message is status text while empty
+func NewHTTPError(status int, message string) *HTTPError {
+	if message == "" {
+		message = http.StatusText(status)
+	}
+
+	return &HTTPError{Status: status, Code: status, Message: message}
+}
+
+// WithCode sets business code
+func (t *HTTPError) WithCode(code int) *HTTPError {
+	t.Code = code
+	return t
+}
+
+// WithDetails sets details responded to client, e.g: invalid fields
+func (t *HTTPError) WithDetails(details interface{}) *HTTPError {
+	t.Details = details
+	return t
+}
+
+// Wrap sets cause of error
+func (t *HTTPError) Wrap(err error) *HTTPError {
+	t.Err = err
+	return t
+}
+
+func (t *HTTPError) Error() string {
+	if t == nil {
+		return "<nil>"
+	}
+	if t.Err != nil {
+		return fmt.Sprintf("%d %s, err=%s", t.Status, t.Message, t.Err.Error())
+	}
+	return fmt.Sprintf("%d %s", t.Status, t.Message)
+}
+
+func (t *HTTPError) Unwrap() error {
+	if t == nil {
+		return nil
+	}
+	return t.Err
+}
+
+// SetErrorRenderer sets ErrorRenderer of default app
+func SetErrorRenderer(r ErrorRenderer) {
+	WagoApp.SetErrorRenderer(r)
+}
+
+// SetResponder sets Responder of default app
+func SetResponder(r Responder) {
+	WagoApp.SetResponder(r)
+}
+
+// SetErrorRenderer sets renderer of errors returned by controller methods, nil means DefaultErrorRenderer
+func (t *Wago) SetErrorRenderer(r ErrorRenderer) {
+	t.errorRenderer = r
+}
+
+// SetResponder sets responder of data returned by controller methods, nil means DefaultResponder
+func (t *Wago) SetResponder(r Responder) {
+	t.responder = r
+}
+
+func (t *Wago) renderError(c *Context, err error) {
+	if t.errorRenderer != nil {
+		t.errorRenderer(c, err)
+		return
+	}
+	DefaultErrorRenderer(c, err)
+}
+
+func (t *Wago) respond(c *Context, data interface{}) {
+	if t.responder != nil {
+		t.responder(c, data)
+		return
+	}
+	DefaultResponder(c, data)
+}
+
+// DefaultResponder responds data as JSON with status 200
+func DefaultResponder(c *Context, data interface{}) {
+	c.JSON(http.StatusOK, data)
+}
+
+// DefaultErrorRenderer responds *HTTPError with its status, ValidationErrors with 422, and other errors with 500, e.g:
+// {"code":404,"message":"user not found","request_id":"..."}
+// {"code":422,"message":"validation failed","details":[{"field":"name","rule":"required","message":"name is required"}],"request_id":"..."}
+func DefaultErrorRenderer(c *Context, err error) {
+	httpErr := toHTTPError(c, err)
+	if c.Writer.Written() {
+		c.Abort()
+		return
+	}
+
+	body := errorBody(c, httpErr.Code, httpErr.Message)
+	if httpErr.Details != nil {
+		body["details"] = httpErr.Details
+	}
+	c.AbortWithStatusJSON(httpErr.Status, body)
+}
+
+// map error to *HTTPError, errors responded with 5xx are logged
+func toHTTPError(c *Context, err error) *HTTPError {
+	httpErr := NewHTTPError(http.StatusInternalServerError, "")
+	var verrs ValidationErrors
+	if e := (*HTTPError)(nil); errors.As(err, &e) && e != nil {
+		httpErr = e
+	} else if errors.As(err, &verrs) {
+		httpErr = NewHTTPError(http.StatusUnprocessableEntity, "validation failed").WithDetails(verrs)
+	}
+
+	if httpErr.Status >= http.StatusInternalServerError {
+		logger.WithFields(logger.Fields{
+			REQUEST_ID:      c.GetString(REQUEST_ID),
+			"route":         c.FullPath(),
+			CONTROLLER_NAME: c.GetString(CONTROLLER_NAME),
+			METHOD_NAME:     c.GetString(METHOD_NAME),
+		}).Errorf("failed to handle request, err=%s", err.Error())
+	}
+
+	return httpErr
+}
+
+// JSON body of error responses
+func errorBody(c *Context, code int, message string) map[string]interface{} {
+	return map[string]interface{}{
+		"code":       code,
+		"message":    message,
+		"request_id": c.GetString(REQUEST_ID),
+	}
+}
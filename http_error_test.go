@@ -0,0 +1,68 @@
+// Copyright 2019 - now The https://github.com/nvwa-io/wago Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wago
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+type nilErrController struct {
+	Controller
+}
+
+func (t *nilErrController) Nil() error {
+	var e *HTTPError
+	return e
+}
+
+func (t *nilErrController) NilData() (map[string]string, error) {
+	var e *HTTPError
+	return map[string]string{"name": "wago"}, e
+}
+
+func TestTypedNilHTTPError(t *testing.T) {
+	var e *HTTPError
+	if e.Error() == "" || e.Unwrap() != nil {
+		t.Fatalf("unexpected nil *HTTPError: %q, %v", e.Error(), e.Unwrap())
+	}
+	if target := (*HTTPError)(nil); !errors.As(error(e), &target) || target != nil {
+		t.Fatalf("unexpected errors.As result: %v", target)
+	}
+
+	app := New(DefaultConfig())
+	app.Server.Use(func(c *Context) {
+		defer func() {
+			if r := recover(); r != nil {
+				t.Errorf("panic: %v", r)
+				panic(r)
+			}
+		}()
+		c.Next()
+	})
+	typ := reflect.TypeOf(nilErrController{})
+	app.Server.GET("/nil", app.HandlerWrapper(typ, "Nil"))
+	app.Server.GET("/nil-data", app.HandlerWrapper(typ, "NilData"))
+
+	for _, path := range []string{"/nil", "/nil-data"} {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, body = %s", path, w.Code, w.Body.String())
+		}
+	}
+}
@@ -14,6 +14,7 @@
 package wago
 
 import (
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"reflect"
 )
@@ -29,17 +30,102 @@ type (
 	WrapperFunc func()
 )
 
+// kinds of values returned by controller methods
+const (
+	// nothing, or values ignored
+	resultNone = iota
+
+	// error
+	resultError
+
+	// (T, error)
+	resultDataError
+)
+
+var (
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// HandlerWrapper transforms controller's method to gin.HandlerFunc of default app
+func HandlerWrapper(controllerType reflect.Type, method string) gin.HandlerFunc {
+	return WagoApp.HandlerWrapper(controllerType, method)
+}
+
 // HandlerWrapper is a wrapper to transform controller'method to gin.HandlerFunc
-// @TODO catch exceptions while method not exist
 // encapsulate controller's method in gin.HandlerFunc
 // means: while gin.HandlerFunc is invoked, the target controller's method will be invoked
-func HandlerWrapper(controllerType reflect.Type, method string) gin.HandlerFunc {
+// method may take a request struct bound from request, refer to bind.go,
+// and may return error or (T, error), which are rendered by app's ErrorRenderer and Responder.
+// it panics while method doesn't exist or isn't able to be a handler.
+func (t *Wago) HandlerWrapper(controllerType reflect.Type, method string) gin.HandlerFunc {
+	m, ok := reflect.PtrTo(controllerType).MethodByName(method)
+	if !ok {
+		panic(fmt.Sprintf("wago: method %s of controller %s not found", method, controllerType.Name()))
+	}
+	kind := resultKind(m.Type)
+	param, err := handlerParam(m.Type)
+	var plan *bindPlan
+	if err == nil && param != nil {
+		plan, err = newBindPlan(param)
+	}
+	if err != nil {
+		panic(fmt.Sprintf("wago: invalid method %s of controller %s, err=%s", method, controllerType.Name(), err.Error()))
+	}
+
 	return func(c *gin.Context) {
+		c.Set(CONTROLLER_NAME, controllerType.Name())
+		c.Set(METHOD_NAME, method)
+
 		ct := reflect.New(controllerType)
 		controller := ct.Interface().(IController)
 		controller.Init(c)
-		m := ct.MethodByName(method)
 
-		m.Call(nil)
+		// request parameter, refer to bind.go
+		args := make([]reflect.Value, 0, 1)
+		if param != nil {
+			v, err := bindParam(c, param, plan)
+			if err != nil {
+				t.renderError(c, err)
+				return
+			}
+			args = append(args, v)
+		}
+
+		out := ct.Method(m.Index).Call(args)
+		t.render(c, kind, out)
+	}
+}
+
+// kind of values returned by method
+func resultKind(m reflect.Type) int {
+	switch {
+	case m.NumOut() == 1 && m.Out(0) == errorType:
+		return resultError
+	case m.NumOut() == 2 && m.Out(1) == errorType:
+		return resultDataError
+	}
+
+	return resultNone
+}
+
+// render values returned by controller method
+func (t *Wago) render(c *Context, kind int, out []reflect.Value) {
+	var err reflect.Value
+	switch kind {
+	case resultError:
+		err = out[0]
+	case resultDataError:
+		err = out[1]
+	default:
+		return
+	}
+
+	// typed nil, e.g: var e *HTTPError; return e, is treated as success
+	if !err.IsNil() && !(err.Elem().Kind() == reflect.Ptr && err.Elem().IsNil()) {
+		t.renderError(c, err.Interface().(error))
+		return
+	}
+	if kind == resultDataError && !c.Writer.Written() {
+		t.respond(c, out[0].Interface())
 	}
 }
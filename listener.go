@@ -0,0 +1,368 @@
+// Copyright 2019 - now The https://github.com/nvwa-io/wago Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wago
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Several listeners can be served by the same app, e.g:
+//
+//	[[server.listeners]]
+//	network = "tcp"
+//	address = ":8080"
+//
+//	[[server.listeners]]
+//	network = "unix"
+//	address = "/var/run/app.sock"
+//	perm    = "0660"
+//
+// systemd socket activation is supported by LISTEN_FDS, sockets passed by systemd are served
+// by "systemd" listeners, or served directly while no listener is configured.
+const (
+	NETWORK_TCP     = "tcp"
+	NETWORK_TLS     = "tls"
+	NETWORK_UNIX    = "unix"
+	NETWORK_SYSTEMD = "systemd"
+
+	// keys of sockets handed off by parent process while restarting, refer to upgrade_unix.go
+	ENV_LISTEN_FDS = ENV_PREFIX + "_LISTEN_FDS"
+
+	// fd of pipe to notify parent process that new process is ready
+	ENV_READY_FD = ENV_PREFIX + "_READY_FD"
+
+	// first file descriptor passed by systemd or parent process
+	listenFdsStart = 3
+)
+
+var (
+	// sockets passed by systemd can only be taken once in process
+	systemdOnce      sync.Once
+	systemdSockets   []namedListener
+	systemdSocketErr error
+
+	// sockets handed off by parent process can only be taken once in process
+	inheritedOnce      sync.Once
+	inheritedMu        sync.Mutex
+	inheritedSockets   map[string]*namedListener
+	inheritedSocketErr error
+)
+
+// listener bound by app
+type namedListener struct {
+	net.Listener
+
+	// configured network, and systemd socket name or configured address
+	network string
+	name    string
+
+	// serve TLS on listener
+	tls bool
+}
+
+// whether any listener serves TLS
+func (t Server) usesTLS() bool {
+	if len(t.Listeners) == 0 {
+		return t.TLS.Enable
+	}
+	for _, l := range t.Listeners {
+		if l.Network == NETWORK_TLS || l.TLS {
+			return true
+		}
+	}
+
+	return false
+}
+
+// bind all listeners of app, bound listeners are closed while any fails
+func (t *Wago) listen(cfg *Config) ([]*namedListener, error) {
+	list := make([]*namedListener, 0)
+	closeAll := func() {
+		for _, l := range list {
+			l.Close()
+		}
+	}
+
+	confs := cfg.Server.Listeners
+	if len(confs) == 0 {
+		sockets, err := takeSystemdSockets()
+		if err != nil {
+			return nil, err
+		}
+		if len(sockets) > 0 {
+			for i := range sockets {
+				sockets[i].tls = cfg.Server.TLS.Enable
+				list = append(list, &sockets[i])
+			}
+			return list, nil
+		}
+
+		network := NETWORK_TCP
+		if cfg.Server.TLS.Enable {
+			network = NETWORK_TLS
+		}
+		confs = []Listener{{
+			Network: network,
+			Address: fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port),
+		}}
+	}
+
+	for _, c := range confs {
+		// sockets handed off by parent process while restarting, refer to upgrade_unix.go
+		if l, err := inheritedListener(c); err != nil || l != nil {
+			if err != nil {
+				closeAll()
+				return nil, err
+			}
+			list = append(list, l)
+			continue
+		}
+
+		ls, err := bindListener(c)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("wago: failed to listen on %s %s, err=%w", c.Network, c.Address, err)
+		}
+		list = append(list, ls...)
+	}
+
+	return list, nil
+}
+
+func bindListener(c Listener) ([]*namedListener, error) {
+	switch c.Network {
+	case NETWORK_TCP, NETWORK_TLS:
+		lc := net.ListenConfig{}
+		if preforkWorkerID() > 0 {
+			// listeners are shared by workers of prefork mode
+			lc.Control = reusePortControl
+		}
+		l, err := lc.Listen(context.Background(), "tcp", c.Address)
+		if err != nil {
+			return nil, err
+		}
+		return []*namedListener{{Listener: l, network: c.Network, name: c.Address, tls: c.Network == NETWORK_TLS || c.TLS}}, nil
+	case NETWORK_UNIX:
+		l, err := listenUnix(c.Address, c.Perm)
+		if err != nil {
+			return nil, err
+		}
+		return []*namedListener{{Listener: l, network: c.Network, name: c.Address, tls: c.TLS}}, nil
+	case NETWORK_SYSTEMD:
+		sockets, err := takeSystemdSockets()
+		if err != nil {
+			return nil, err
+		}
+		list := make([]*namedListener, 0)
+		for i := range sockets {
+			if c.Address == "" || sockets[i].name == c.Address {
+				sockets[i].tls = c.TLS
+				list = append(list, &sockets[i])
+			}
+		}
+		if len(list) == 0 {
+			return nil, errors.New("no socket passed by systemd")
+		}
+		return list, nil
+	}
+
+	return nil, fmt.Errorf("unsupported network %q", c.Network)
+}
+
+// listen on unix socket, stale socket file left by crashed process is removed
+func listenUnix(path, perm string) (net.Listener, error) {
+	mode, err := parsePerm(perm)
+	if err != nil {
+		return nil, err
+	}
+
+	if fi, err := os.Stat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("socket %s is in use", path)
+		}
+		os.Remove(path)
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if mode != 0 {
+		if err := os.Chmod(path, mode); err != nil {
+			l.Close()
+			return nil, err
+		}
+	}
+
+	return l, nil
+}
+
+// parse octal file mode, e.g: "0660", 0 means default
+func parsePerm(perm string) (os.FileMode, error) {
+	if perm == "" {
+		return 0, nil
+	}
+	mode, err := strconv.ParseUint(perm, 8, 32)
+	if err != nil {
+		return 0, err
+	}
+
+	return os.FileMode(mode), nil
+}
+
+// take sockets passed by systemd, only once in process
+func takeSystemdSockets() ([]namedListener, error) {
+	systemdOnce.Do(func() {
+		systemdSockets, systemdSocketErr = systemdListeners()
+		if systemdSocketErr != nil || len(systemdSockets) > 0 {
+			return
+		}
+
+		// systemd sockets handed off by parent process while restarting
+		inherited, err := takeInheritedListeners()
+		if err != nil {
+			systemdSocketErr = err
+			return
+		}
+		inheritedMu.Lock()
+		defer inheritedMu.Unlock()
+		for key, l := range inherited {
+			if l.network == NETWORK_SYSTEMD {
+				systemdSockets = append(systemdSockets, *l)
+				delete(inherited, key)
+			}
+		}
+	})
+
+	return systemdSockets, systemdSocketErr
+}
+
+// sockets passed by systemd socket activation, refer to sd_listen_fds(3)
+func systemdListeners() ([]namedListener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil, nil
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	// not passed to child processes
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	list := make([]namedListener, 0, n)
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("LISTEN_FD_%d", listenFdsStart+i)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+
+		f := os.NewFile(uintptr(listenFdsStart+i), name)
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("wago: invalid socket %s passed by systemd, err=%w", name, err)
+		}
+		list = append(list, namedListener{Listener: l, network: NETWORK_SYSTEMD, name: name})
+	}
+
+	return list, nil
+}
+
+// take sockets handed off by parent process, only once in process.
+// parent process passes sockets from fd 3, and their keys by ENV_LISTEN_FDS, e.g:
+// WAGO_LISTEN_FDS="tcp=:8080;unix=/var/run/app.sock;systemd=web"
+func takeInheritedListeners() (map[string]*namedListener, error) {
+	inheritedOnce.Do(func() {
+		inheritedSockets, inheritedSocketErr = inheritedListeners()
+	})
+
+	return inheritedSockets, inheritedSocketErr
+}
+
+// socket handed off by parent process for configured listener, nil while not found
+func inheritedListener(c Listener) (*namedListener, error) {
+	inherited, err := takeInheritedListeners()
+	if err != nil {
+		return nil, err
+	}
+
+	inheritedMu.Lock()
+	defer inheritedMu.Unlock()
+
+	key := listenerKey(c.Network, c.Address)
+	l, ok := inherited[key]
+	if !ok {
+		return nil, nil
+	}
+	delete(inherited, key)
+	l.tls = c.Network == NETWORK_TLS || c.TLS
+
+	return l, nil
+}
+
+// number of sockets handed off by parent process but not served yet
+func inheritedPending() int {
+	inherited, err := takeInheritedListeners()
+	if err != nil {
+		return 0
+	}
+
+	inheritedMu.Lock()
+	defer inheritedMu.Unlock()
+
+	return len(inherited)
+}
+
+func inheritedListeners() (map[string]*namedListener, error) {
+	m := make(map[string]*namedListener)
+	v := os.Getenv(ENV_LISTEN_FDS)
+	if v == "" {
+		return m, nil
+	}
+	os.Unsetenv(ENV_LISTEN_FDS)
+
+	for i, key := range strings.Split(v, ";") {
+		kv := strings.SplitN(key, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("wago: invalid %s=%q", ENV_LISTEN_FDS, v)
+		}
+
+		f := os.NewFile(uintptr(listenFdsStart+i), key)
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("wago: invalid socket %s inherited from parent process, err=%w", key, err)
+		}
+		m[key] = &namedListener{Listener: l, network: kv[0], name: kv[1]}
+	}
+
+	return m, nil
+}
+
+func listenerKey(network, address string) string {
+	return network + "=" + address
+}
@@ -75,6 +75,29 @@ func AddHook(hook Hook) {
 	std.AddHook(hook)
 }
 
+// AddFields adds fields to every entry of the standard logger, e.g: worker id of prefork mode.
+// fields set by WithField/WithFields aren't overridden.
+func AddFields(fields Fields) {
+	std.AddHook(&fieldsHook{fields: fields})
+}
+
+type fieldsHook struct {
+	fields Fields
+}
+
+func (t *fieldsHook) Levels() []Level {
+	return logrus.AllLevels
+}
+
+func (t *fieldsHook) Fire(entry *Entry) error {
+	for k, v := range t.fields {
+		if _, ok := entry.Data[k]; !ok {
+			entry.Data[k] = v
+		}
+	}
+	return nil
+}
+
 // WithError creates an entry from the standard logger and adds an error to it, using the value defined in ErrorKey as key.
 func WithError(err error) *logrus.Entry {
 	return std.WithField(logrus.ErrorKey, err)
@@ -0,0 +1,11 @@
+package middleware
+
+import (
+	"github.com/nvwa-io/wago"
+)
+
+// recover panics of handlers, log them and respond 500 with request id.
+// notice: it's registered by wago.New, only needed by custom gin engines.
+func Recovery() wago.MiddleWareHandler {
+	return wago.Recovery()
+}
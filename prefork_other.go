@@ -0,0 +1,47 @@
+// Copyright 2019 - now The https://github.com/nvwa-io/wago Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package wago
+
+import (
+	"context"
+	"errors"
+	"syscall"
+)
+
+// prefork isn't supported on current platform, refer to prefork_unix.go
+const (
+	ENV_PREFORK_WORKER = ENV_PREFIX + "_PREFORK_WORKER"
+	LOG_FIELD_WORKER   = "worker"
+
+	preforkSupported = false
+)
+
+func preforkWorkerID() int {
+	return 0
+}
+
+func (t *Wago) runPrefork(ctx context.Context) error {
+	return errors.New("wago: prefork isn't supported on current platform")
+}
+
+func withPreforkMaster(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithCancel(ctx)
+}
+
+func reusePortControl(network, address string, c syscall.RawConn) error {
+	return nil
+}
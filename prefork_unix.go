@@ -0,0 +1,320 @@
+// Copyright 2019 - now The https://github.com/nvwa-io/wago Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+// +build linux darwin dragonfly freebsd netbsd openbsd
+
+package wago
+
+import (
+	"context"
+	"fmt"
+	"github.com/nvwa-io/wago/logger"
+	"golang.org/x/sys/unix"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Prefork mode, e.g: [server] prefork = 4
+// master process starts N worker processes of the same binary, and workers bind the same tcp/tls listeners
+// with SO_REUSEPORT, so that connections are balanced by kernel.
+// master restarts crashed workers with backoff, and forwards SIGINT/SIGTERM/SIGHUP to workers.
+// master returns error while any worker fails to start, so that errors like "address already in use" reach caller.
+// workers exit while master exits, and log with field "worker" = worker id.
+// zero-downtime restart by SIGUSR2 isn't supported, SIGUSR2 is logged and ignored by master and workers.
+const (
+	// id of worker process, set by master
+	ENV_PREFORK_WORKER = ENV_PREFIX + "_PREFORK_WORKER"
+
+	// log field of worker id
+	LOG_FIELD_WORKER = "worker"
+
+	// seconds to wait before restarting crashed worker, doubled by consecutive failures
+	preforkRestartDelay    = 1
+	preforkMaxRestartDelay = 30
+
+	// max consecutive failures of restarting worker before master exits
+	preforkMaxRestarts = 5
+
+	preforkSupported = true
+)
+
+// worker process supervised by master
+type preforkWorker struct {
+	cmd *exec.Cmd
+
+	// incremented while worker is restarted, events of former processes are ignored
+	gen int
+
+	// whether worker is serving
+	ready bool
+
+	// consecutive failures before ready
+	failures int
+}
+
+// worker is ready or exited
+type preforkEvent struct {
+	id  int
+	gen int
+	err error
+}
+
+// id of current worker process, 0 means current process isn't a worker
+func preforkWorkerID() int {
+	id, _ := strconv.Atoi(os.Getenv(ENV_PREFORK_WORKER))
+	return id
+}
+
+// run as master: start workers and supervise them until ctx is done.
+// error is returned while any worker exits before ready on startup, e.g: address in use,
+// or a crashed worker fails to restart for preforkMaxRestarts times.
+func (t *Wago) runPrefork(ctx context.Context) error {
+	cfg := t.Config()
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	configLogger(cfg.Log)
+	configLogOutput(cfg.Log, consoleOutput(cfg.Server))
+
+	// generate development certificate before workers, which share it
+	if cfg.Server.usesTLS() {
+		if _, err := cfg.tlsConfig(); err != nil {
+			return err
+		}
+	}
+
+	path, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	env := make([]string, 0, len(os.Environ())+2)
+	for _, e := range os.Environ() {
+		if !strings.HasPrefix(e, ENV_PREFORK_WORKER+"=") && !strings.HasPrefix(e, ENV_READY_FD+"=") {
+			env = append(env, e)
+		}
+	}
+
+	n := cfg.Server.Prefork
+	workers := make(map[int]*preforkWorker, n)
+	exited := make(chan preforkEvent, n)
+	ready := make(chan preforkEvent, n)
+	restart := make(chan int, n)
+	start := func(id int) error {
+		w, ok := workers[id]
+		if !ok {
+			w = &preforkWorker{}
+			workers[id] = w
+		}
+
+		// worker notifies master by pipe while serving, refer to notifyReady
+		r, pw, err := os.Pipe()
+		if err != nil {
+			return err
+		}
+		defer pw.Close()
+
+		cmd := exec.Command(path, os.Args[1:]...)
+		cmd.Env = append(env,
+			fmt.Sprintf("%s=%d", ENV_PREFORK_WORKER, id),
+			fmt.Sprintf("%s=%d", ENV_READY_FD, listenFdsStart),
+		)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		cmd.ExtraFiles = []*os.File{pw}
+		// signals from terminal are forwarded by master
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		if err := cmd.Start(); err != nil {
+			r.Close()
+			return err
+		}
+
+		w.cmd, w.ready = cmd, false
+		w.gen++
+		gen := w.gen
+		go func() {
+			defer r.Close()
+			if _, err := r.Read(make([]byte, 1)); err == nil {
+				ready <- preforkEvent{id: id, gen: gen}
+			}
+		}()
+		go func() {
+			exited <- preforkEvent{id: id, gen: gen, err: cmd.Wait()}
+		}()
+		logger.Infof("worker %d started, pid=%d", id, cmd.Process.Pid)
+		return nil
+	}
+
+	for id := 1; id <= n; id++ {
+		if err := start(id); err != nil {
+			stopWorkers(workers, exited, 0)
+			return fmt.Errorf("wago: failed to start worker %d, err=%w", id, err)
+		}
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	// default action of SIGUSR2 terminates master, and all workers with it
+	usr2 := make(chan os.Signal, 1)
+	signal.Notify(usr2, syscall.SIGUSR2)
+	defer signal.Stop(usr2)
+
+	starting := true
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Infof("%s is shutting down %d workers", cfg.App.App, n)
+			stopWorkers(workers, exited, cfg.Server.ShutdownTimeout)
+			logger.Infof("%s finished", cfg.App.App)
+			return nil
+		case <-usr2:
+			logger.Errorf("zero-downtime restart isn't supported in prefork mode, SIGUSR2 is ignored")
+		case <-hup:
+			for _, w := range workers {
+				if w.cmd != nil {
+					w.cmd.Process.Signal(syscall.SIGHUP)
+				}
+			}
+		case e := <-ready:
+			w := workers[e.id]
+			if e.gen != w.gen || w.cmd == nil {
+				continue
+			}
+			w.ready, w.failures = true, 0
+			if starting && allWorkersReady(workers) {
+				starting = false
+				logger.Infof("%s is serving with %d workers", cfg.App.App, n)
+			}
+		case e := <-exited:
+			w := workers[e.id]
+			if e.gen != w.gen {
+				continue
+			}
+			w.cmd = nil
+
+			// fail fast while workers can't start, e.g: address in use or OnBoot error
+			if !w.ready {
+				w.failures++
+				if starting || w.failures > preforkMaxRestarts {
+					stopWorkers(workers, exited, cfg.Server.ShutdownTimeout)
+					return fmt.Errorf("wago: worker %d exited before ready for %d times, err=%v", e.id, w.failures, e.err)
+				}
+			}
+
+			delay := preforkRestartDelay * time.Second << uint(w.failures)
+			if delay > preforkMaxRestartDelay*time.Second {
+				delay = preforkMaxRestartDelay * time.Second
+			}
+			logger.Errorf("worker %d exited, restarting in %s, err=%v", e.id, delay, e.err)
+			id := e.id
+			time.AfterFunc(delay, func() {
+				restart <- id
+			})
+		case id := <-restart:
+			if err := start(id); err != nil {
+				stopWorkers(workers, exited, cfg.Server.ShutdownTimeout)
+				return fmt.Errorf("wago: failed to restart worker %d, err=%w", id, err)
+			}
+		}
+	}
+}
+
+func allWorkersReady(workers map[int]*preforkWorker) bool {
+	for _, w := range workers {
+		if !w.ready {
+			return false
+		}
+	}
+	return true
+}
+
+// send SIGTERM to running workers, and kill them after timeout seconds
+func stopWorkers(workers map[int]*preforkWorker, exited <-chan preforkEvent, timeout int) {
+	running := make(map[int]*preforkWorker)
+	for id, w := range workers {
+		if w.cmd != nil {
+			w.cmd.Process.Signal(syscall.SIGTERM)
+			running[id] = w
+		}
+	}
+
+	if timeout <= 0 {
+		timeout = DEFAULT_SHUTDOWN_TIMEOUT
+	}
+	deadline := time.After(time.Duration(timeout) * time.Second)
+	for len(running) > 0 {
+		select {
+		case e := <-exited:
+			if w, ok := running[e.id]; ok && w.gen == e.gen {
+				w.cmd = nil
+				delete(running, e.id)
+			}
+		case <-deadline:
+			for id, w := range running {
+				logger.Errorf("worker %d didn't stop in %d seconds, killed", id, timeout)
+				w.cmd.Process.Kill()
+			}
+			return
+		}
+	}
+}
+
+// context of worker, done while master exits
+func withPreforkMaster(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	ppid := os.Getppid()
+
+	// workers don't restart by SIGUSR2, ignore it rather than exit
+	usr2 := make(chan os.Signal, 1)
+	signal.Notify(usr2, syscall.SIGUSR2)
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		defer signal.Stop(usr2)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-usr2:
+				logger.Errorf("zero-downtime restart isn't supported in prefork mode, SIGUSR2 is ignored")
+			case <-ticker.C:
+				if os.Getppid() != ppid {
+					logger.Errorf("master %d exited", ppid)
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	return ctx, cancel
+}
+
+// set SO_REUSEPORT on listeners of workers
+func reusePortControl(network, address string, c syscall.RawConn) error {
+	var err error
+	if e := c.Control(func(fd uintptr) {
+		err = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+	}); e != nil {
+		return e
+	}
+
+	return err
+}
@@ -0,0 +1,130 @@
+// Copyright 2019 - now The https://github.com/nvwa-io/wago Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wago
+
+import (
+	"errors"
+	"fmt"
+	"github.com/nvwa-io/wago/logger"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"syscall"
+)
+
+// Panics of handlers are recovered by Recovery middleware, which is registered by New.
+// panic is logged with request id, route, controller, method and trimmed stack,
+// and a JSON 500 response with request id is returned, e.g:
+// {"code":500,"message":"Internal Server Error","request_id":"..."}
+// stack is included in response while RunMode is debug and App.RecoveryStack is true.
+const (
+	// max stack frames logged and responded
+	recoveryMaxFrames = 32
+)
+
+// Recovery recovers panics of default app's handlers
+func Recovery() MiddleWareHandler {
+	return WagoApp.Recovery()
+}
+
+// Recovery recovers panics of handlers, and responds 500
+func (t *Wago) Recovery() MiddleWareHandler {
+	return func(c *Context) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			// ErrAbortHandler aborts response silently, refer to net/http
+			if r == http.ErrAbortHandler {
+				panic(r)
+			}
+
+			stack := panicStack(3)
+			fields := logger.Fields{
+				REQUEST_ID:      c.GetString(REQUEST_ID),
+				"method":        c.Request.Method,
+				"path":          c.Request.URL.Path,
+				"route":         c.FullPath(),
+				CONTROLLER_NAME: c.GetString(CONTROLLER_NAME),
+				METHOD_NAME:     c.GetString(METHOD_NAME),
+				"stack":         strings.Join(stack, "\n"),
+			}
+
+			// connection is closed by client, nothing can be responded
+			if isBrokenPipe(r) {
+				logger.WithFields(fields).Warnf("connection closed by client: %v", r)
+				c.Abort()
+				return
+			}
+			logger.WithFields(fields).Errorf("panic recovered: %v", r)
+
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+
+			body := errorBody(c, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+			if cfg := t.Config(); cfg.App.RunMode == RUN_MODE_DEBUG && cfg.App.RecoveryStack {
+				body["panic"] = fmt.Sprint(r)
+				body["stack"] = stack
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, body)
+		}()
+
+		c.Next()
+	}
+}
+
+// stack of panic, frames of runtime and below gin engine are trimmed
+func panicStack(skip int) []string {
+	pcs := make([]uintptr, recoveryMaxFrames+16)
+	n := runtime.Callers(skip, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	stack := make([]string, 0, recoveryMaxFrames)
+	for len(stack) < recoveryMaxFrames {
+		frame, more := frames.Next()
+		if strings.HasPrefix(frame.Function, "github.com/gin-gonic/gin.(*Engine)") {
+			break
+		}
+		if !strings.HasPrefix(frame.Function, "runtime.") {
+			stack = append(stack, fmt.Sprintf("%s %s:%d", frame.Function, frame.File, frame.Line))
+		}
+		if !more {
+			break
+		}
+	}
+
+	return stack
+}
+
+// whether panic is caused by connection closed by client
+func isBrokenPipe(r interface{}) bool {
+	err, ok := r.(error)
+	if !ok {
+		return false
+	}
+
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		return false
+	}
+	var syscallErr *os.SyscallError
+
+	return errors.As(opErr, &syscallErr) &&
+		(errors.Is(syscallErr.Err, syscall.EPIPE) || errors.Is(syscallErr.Err, syscall.ECONNRESET))
+}
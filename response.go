@@ -0,0 +1,102 @@
+// Copyright 2019 - now The https://github.com/nvwa-io/wago Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wago
+
+import (
+	"net/http"
+)
+
+// Responses of Controller helpers are wrapped in an envelope with field names set in [app.response], e.g:
+// t.OK(user)                 => {"code":0,"message":"ok","data":{...},"request_id":"..."}
+// t.Fail(10001, "no quota")  => {"code":10001,"message":"no quota","request_id":"..."}
+// t.Page(users, 100, 1, 20)  => {"code":0,"message":"ok","data":{"items":[...],"total":100,"page":1,"size":20},"request_id":"..."}
+// use EnvelopeResponder and EnvelopeErrorRenderer to wrap values returned by controller methods in the same envelope.
+
+// Envelope builds response body by [app.response] of app serving current request, data is omitted while nil
+func Envelope(c *Context, code int, message string, data interface{}) map[string]interface{} {
+	r := AppFromContext(c).Config().App.Response
+
+	body := make(map[string]interface{}, 4)
+	setEnvelopeField(body, r.CodeField, code)
+	setEnvelopeField(body, r.MessageField, message)
+	if data != nil {
+		setEnvelopeField(body, r.DataField, data)
+	}
+	setEnvelopeField(body, r.RequestIdField, c.GetString(REQUEST_ID))
+
+	return body
+}
+
+// PageData builds page data by [app.response] of app serving current request
+func PageData(c *Context, items interface{}, total int64, page, size int) map[string]interface{} {
+	r := AppFromContext(c).Config().App.Response
+
+	data := make(map[string]interface{}, 4)
+	setEnvelopeField(data, r.ItemsField, items)
+	setEnvelopeField(data, r.TotalField, total)
+	setEnvelopeField(data, r.PageField, page)
+	setEnvelopeField(data, r.SizeField, size)
+
+	return data
+}
+
+func setEnvelopeField(m map[string]interface{}, name string, value interface{}) {
+	if name != "-" {
+		m[name] = value
+	}
+}
+
+// EnvelopeResponder responds data returned by controller methods in envelope with status 200
+func EnvelopeResponder(c *Context, data interface{}) {
+	r := AppFromContext(c).Config().App.Response
+	c.JSON(http.StatusOK, Envelope(c, r.SuccessCode, r.SuccessMessage, data))
+}
+
+// EnvelopeErrorRenderer responds errors returned by controller methods in envelope,
+// status, code and message are mapped like DefaultErrorRenderer, details are responded as data
+func EnvelopeErrorRenderer(c *Context, err error) {
+	httpErr := toHTTPError(c, err)
+	if c.Writer.Written() {
+		c.Abort()
+		return
+	}
+
+	c.AbortWithStatusJSON(httpErr.Status, Envelope(c, httpErr.Code, httpErr.Message, httpErr.Details))
+}
+
+// OK responds data in envelope with status 200
+func (t *Controller) OK(data interface{}) {
+	EnvelopeResponder(t.Ctx, data)
+}
+
+// Created responds data in envelope with status 201
+func (t *Controller) Created(data interface{}) {
+	r := AppFromContext(t.Ctx).Config().App.Response
+	t.Ctx.JSON(http.StatusCreated, Envelope(t.Ctx, r.SuccessCode, r.SuccessMessage, data))
+}
+
+// Page responds page data in envelope with status 200
+func (t *Controller) Page(items interface{}, total int64, page, size int) {
+	t.OK(PageData(t.Ctx, items, total, page, size))
+}
+
+// Fail responds business code and message in envelope with status App.Response.FailStatus, 200 by default
+func (t *Controller) Fail(code int, message string) {
+	t.FailWithStatus(AppFromContext(t.Ctx).Config().App.Response.FailStatus, code, message)
+}
+
+// FailWithStatus responds business code and message in envelope with status
+func (t *Controller) FailWithStatus(status, code int, message string) {
+	t.Ctx.AbortWithStatusJSON(status, Envelope(t.Ctx, code, message, nil))
+}
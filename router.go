@@ -93,10 +93,10 @@ func (t *RouterGroup) Use(middleWares ...MiddleWareHandler) *RouterGroup {
 	return t
 }
 
-// Config to WagoApp.Server.Group()
+// Config to app.Server.Group()
 // run while wago app boot
-func (t *RouterGroup) config() {
-	group := WagoApp.Server.Group(t.prefix, t.middleWares...)
+func (t *RouterGroup) config(app *Wago) {
+	group := app.Server.Group(t.prefix, t.middleWares...)
 	if t.prefix == "" {
 		t.prefix = "/"
 	}
@@ -111,11 +111,11 @@ func (t *RouterGroup) config() {
 
 	// register routers
 	for _, c := range t.controllers {
-		switch AppConfig.App.RouterMode {
+		switch app.config.App.RouterMode {
 		case ROUTER_MODE_COMMENT:
-			t.registerRouterByComment(c, group)
+			t.registerRouterByComment(app, c, group)
 		default:
-			t.registerRouterByAuto(c, group)
+			t.registerRouterByAuto(app, c, group)
 		}
 	}
 }
@@ -125,7 +125,7 @@ func (t *RouterGroup) config() {
 // so we aren't able to reflect to get comment info at runtime
 // so here, we use golang's ast pkg to parse controllers' *.go file to auto-generate router configuration codes
 // refer to comment.go
-func (t *RouterGroup) registerRouterByComment(c IController, group *gin.RouterGroup) {
+func (t *RouterGroup) registerRouterByComment(app *Wago, c IController, group *gin.RouterGroup) {
 	v := reflect.ValueOf(c)
 	vi := reflect.Indirect(v)
 
@@ -143,7 +143,7 @@ func (t *RouterGroup) registerRouterByComment(c IController, group *gin.RouterGr
 
 		for _, v := range rs {
 			for _, hm := range v.HTTPMethod {
-				group.Handle(hm, v.Router, HandlerWrapper(vi.Type(), v.Method))
+				group.Handle(hm, v.Router, app.HandlerWrapper(vi.Type(), v.Method))
 			}
 		}
 	}
@@ -157,13 +157,14 @@ func (t *RouterGroup) registerRouterByComment(c IController, group *gin.RouterGr
 
 // while sep = 0 (means no config for router separator), use struct method name as router path
 // while sep equal '-' or '_', use snake string as router path
-func (t *RouterGroup) registerRouterByAuto(c IController, group *gin.RouterGroup) {
+func (t *RouterGroup) registerRouterByAuto(app *Wago, c IController, group *gin.RouterGroup) {
+	cfg := app.config
 	v := reflect.ValueOf(c)
 	vi := reflect.Indirect(v)
 	typ := reflect.TypeOf(c)
 	var sep byte
-	if len(AppConfig.App.RouterSep) > 0 {
-		if AppConfig.App.RouterSep[0] == '_' {
+	if len(cfg.App.RouterSep) > 0 {
+		if cfg.App.RouterSep[0] == '_' {
 			sep = '_'
 		} else {
 			sep = '-'
@@ -172,7 +173,7 @@ func (t *RouterGroup) registerRouterByAuto(c IController, group *gin.RouterGroup
 
 	// pkg path
 	routerPathPkg := ""
-	cntlSep := fmt.Sprintf("%s%s%s", string(os.PathSeparator), AppConfig.App.ControllerPath, string(os.PathSeparator))
+	cntlSep := fmt.Sprintf("%s%s%s", string(os.PathSeparator), cfg.App.ControllerPath, string(os.PathSeparator))
 	arr := strings.Split(vi.Type().PkgPath(), cntlSep)
 	if len(arr) <= 1 {
 		routerPathPkg = "/"
@@ -194,6 +195,11 @@ func (t *RouterGroup) registerRouterByAuto(c IController, group *gin.RouterGroup
 			continue
 		}
 
+		// skip methods which aren't able to be handlers, e.g: helpers with several parameters
+		if _, err := handlerParam(typ.Method(i).Type); err != nil {
+			continue
+		}
+
 		routerPathMethod := methodName
 		mHttpMethods := make([]string, 0)
 
@@ -220,7 +226,7 @@ func (t *RouterGroup) registerRouterByAuto(c IController, group *gin.RouterGroup
 			strings.Trim(routerPathMethod, "/"))
 		requestPath = "/" + strings.TrimLeft(requestPath, "/") // maybe rootPathPkg = "/"
 		for _, hm := range mHttpMethods {
-			group.Handle(hm, requestPath, HandlerWrapper(vi.Type(), methodName))
+			group.Handle(hm, requestPath, app.HandlerWrapper(vi.Type(), methodName))
 		}
 	}
 }
@@ -0,0 +1,157 @@
+// Copyright 2019 - now The https://github.com/nvwa-io/wago Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wago
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+var (
+	tlsVersions = map[string]uint16{
+		"1.0": tls.VersionTLS10,
+		"1.1": tls.VersionTLS11,
+		"1.2": tls.VersionTLS12,
+		"1.3": tls.VersionTLS13,
+	}
+
+	tlsClientAuth = map[string]tls.ClientAuthType{
+		"request":            tls.RequestClientCert,
+		"require":            tls.RequireAnyClientCert,
+		"verify_if_given":    tls.VerifyClientCertIfGiven,
+		"require_and_verify": tls.RequireAndVerifyClientCert,
+	}
+)
+
+// certReloader keeps the current server certificate,
+// which can be reloaded from disk without restarting the server.
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// reload certificate and private key from disk,
+// the previous certificate is kept while failed.
+func (t *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(t.certFile, t.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate %s, err=%s", t.certFile, err.Error())
+	}
+
+	t.mu.Lock()
+	t.cert = &cert
+	t.mu.Unlock()
+
+	return nil
+}
+
+// GetCertificate is used as tls.Config.GetCertificate
+func (t *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.cert, nil
+}
+
+// build *tls.Config from [server.tls] configuration
+func newTLSConfig(c TLS) (*tls.Config, *certReloader, error) {
+	reloader, err := newCertReloader(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conf := &tls.Config{
+		GetCertificate: reloader.GetCertificate,
+		MinVersion:     tls.VersionTLS12,
+	}
+
+	if c.MinVersion != "" {
+		v, ok := tlsVersions[c.MinVersion]
+		if !ok {
+			return nil, nil, fmt.Errorf("invalid TLS MinVersion: %s", c.MinVersion)
+		}
+		conf.MinVersion = v
+	}
+
+	if len(c.CipherSuites) > 0 {
+		conf.CipherSuites, err = parseCipherSuites(c.CipherSuites)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if c.ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(c.ClientCAFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read client CA file %s, err=%s", c.ClientCAFile, err.Error())
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, nil, fmt.Errorf("no valid certificate found in client CA file %s", c.ClientCAFile)
+		}
+		conf.ClientCAs = pool
+		conf.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	if c.ClientAuth != "" {
+		auth, ok := tlsClientAuth[strings.ToLower(c.ClientAuth)]
+		if !ok {
+			return nil, nil, fmt.Errorf("invalid TLS ClientAuth: %s", c.ClientAuth)
+		}
+		conf.ClientAuth = auth
+	}
+
+	return conf, reloader, nil
+}
+
+// transform cipher suite names to IDs
+func parseCipherSuites(names []string) ([]uint16, error) {
+	suites := make(map[string]uint16)
+	for _, s := range tls.CipherSuites() {
+		suites[s.Name] = s.ID
+	}
+	for _, s := range tls.InsecureCipherSuites() {
+		suites[s.Name] = s.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := suites[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported TLS cipher suite: %s", name)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,259 @@
+// Copyright 2019 - now The https://github.com/nvwa-io/wago Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+// +build !windows
+
+package wago
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/nvwa-io/wago/logger"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Zero-downtime restart, e.g: replace binary then `kill -USR2 <pid>`
+// 1. running process starts the binary with same arguments, listening sockets are passed as inherited fds
+// 2. new process serves on inherited sockets instead of binding, then notifies parent process by pipe
+// 3. running process stops accepting, drains in-flight requests and exits
+// listeners of all apps running in process are handed off together.
+// running process keeps serving while new process fails to start.
+// not supported in prefork mode, SIGUSR2 is ignored by master and workers, refer to prefork_unix.go
+const (
+	// seconds to wait for new process to be ready
+	DEFAULT_UPGRADE_TIMEOUT = 60
+)
+
+// file descriptor of listener
+type filer interface {
+	File() (*os.File, error)
+}
+
+var (
+	// SIGUSR2 is handled once in process, listeners of all running apps are handed off to one new process
+	processUpgrader = &upgrader{apps: make(map[*Wago]*upgradeApp)}
+
+	// notify parent process only once
+	readyMu sync.Mutex
+)
+
+// running apps of process
+type upgrader struct {
+	mu   sync.Mutex
+	apps map[*Wago]*upgradeApp
+
+	// stop watching SIGUSR2 while no app is running
+	stop chan struct{}
+}
+
+type upgradeApp struct {
+	listeners []*namedListener
+
+	// closed once new process is ready
+	done chan<- struct{}
+}
+
+// restart on SIGUSR2 until ctx is done, done is closed once new process is ready
+func (t *Wago) watchUpgrade(ctx context.Context, listeners []*namedListener, done chan<- struct{}) {
+	processUpgrader.register(t, &upgradeApp{listeners: listeners, done: done})
+	<-ctx.Done()
+	processUpgrader.unregister(t)
+}
+
+func (t *upgrader) register(app *Wago, a *upgradeApp) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.apps[app] = a
+	if t.stop == nil {
+		t.stop = make(chan struct{})
+		go t.watch(t.stop)
+	}
+}
+
+func (t *upgrader) unregister(app *Wago) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.apps, app)
+	if len(t.apps) == 0 && t.stop != nil {
+		close(t.stop)
+		t.stop = nil
+	}
+}
+
+func (t *upgrader) watch(stop <-chan struct{}) {
+	usr2 := make(chan os.Signal, 1)
+	signal.Notify(usr2, syscall.SIGUSR2)
+	defer signal.Stop(usr2)
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-usr2:
+			t.upgrade()
+		}
+	}
+}
+
+// hand off listeners of all running apps to one new process, then notify apps to shut down
+func (t *upgrader) upgrade() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	listeners := make([]*namedListener, 0)
+	for app, a := range t.apps {
+		logger.Infof("%s is restarting", app.Config().App.App)
+		listeners = append(listeners, a.listeners...)
+	}
+	if err := upgrade(listeners); err != nil {
+		logger.Errorf("failed to restart, err=%s", err.Error())
+		return
+	}
+
+	for app, a := range t.apps {
+		close(a.done)
+		delete(t.apps, app)
+	}
+}
+
+// start new process with listeners and wait for it to be ready
+func upgrade(listeners []*namedListener) error {
+	path, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	files := make([]*os.File, 0, len(listeners)+1)
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
+	keys := make([]string, 0, len(listeners))
+	handed := make(map[string]bool, len(listeners))
+	for _, l := range listeners {
+		// systemd sockets may be shared by apps
+		key := listenerKey(l.network, l.name)
+		if handed[key] {
+			continue
+		}
+		handed[key] = true
+
+		fl, ok := l.Listener.(filer)
+		if !ok {
+			return fmt.Errorf("listener %s can't be handed off", l.name)
+		}
+		f, err := fl.File()
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+		keys = append(keys, key)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	files = append(files, w)
+
+	env := make([]string, 0, len(os.Environ())+2)
+	for _, e := range os.Environ() {
+		if !strings.HasPrefix(e, ENV_LISTEN_FDS+"=") && !strings.HasPrefix(e, ENV_READY_FD+"=") {
+			env = append(env, e)
+		}
+	}
+	env = append(env,
+		ENV_LISTEN_FDS+"="+strings.Join(keys, ";"),
+		ENV_READY_FD+"="+strconv.Itoa(listenFdsStart+len(keys)),
+	)
+
+	cmd := exec.Command(path, os.Args[1:]...)
+	cmd.Env = env
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.ExtraFiles = files
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// pipe is closed while new process exits before ready
+	w.Close()
+	files = files[:len(files)-1]
+
+	ready := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 1)
+		_, err := r.Read(buf)
+		ready <- err
+	}()
+
+	select {
+	case err := <-ready:
+		if err != nil {
+			cmd.Wait()
+			return fmt.Errorf("new process %d exited before ready", cmd.Process.Pid)
+		}
+	case <-time.After(DEFAULT_UPGRADE_TIMEOUT * time.Second):
+		cmd.Process.Kill()
+		cmd.Wait()
+		return errors.New("timeout to wait for new process to be ready")
+	}
+	go cmd.Wait()
+
+	// unix socket files are served by new process
+	for _, l := range listeners {
+		if ul, ok := l.Listener.(*net.UnixListener); ok {
+			ul.SetUnlinkOnClose(false)
+		}
+	}
+	logger.Infof("new process %d is ready", cmd.Process.Pid)
+
+	return nil
+}
+
+// notify parent process that current process is serving, while started by restarting.
+// it's called by every app after serving, parent process is notified once all handed off sockets are served.
+func notifyReady() {
+	readyMu.Lock()
+	defer readyMu.Unlock()
+
+	v := os.Getenv(ENV_READY_FD)
+	if v == "" || inheritedPending() > 0 {
+		return
+	}
+	os.Unsetenv(ENV_READY_FD)
+
+	fd, err := strconv.Atoi(v)
+	if err != nil {
+		logger.Errorf("invalid %s=%q", ENV_READY_FD, v)
+		return
+	}
+	f := os.NewFile(uintptr(fd), "ready")
+	defer f.Close()
+	if _, err := f.Write([]byte{1}); err != nil {
+		logger.Errorf("failed to notify parent process, err=%s", err.Error())
+	}
+}
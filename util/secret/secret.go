@@ -0,0 +1,150 @@
+// Copyright 2019 - now The https://github.com/nvwa-io/wago Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package secret encrypts and decrypts config values, e.g:
+// password = "enc:v1:9XQ2...=="
+// use `wago config encrypt` / `wago config decrypt` to produce and inspect values.
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// values are encrypted by AES-256-GCM with the key from environment variable
+// WAGO_CONFIG_KEY, or the file set by WAGO_CONFIG_KEY_FILE.
+// key is 32 bytes encoded by base64, use `wago config genkey` to generate one.
+const (
+	PREFIX_V1 = "enc:v1:"
+
+	KEY_ENV      = "WAGO_CONFIG_KEY"
+	KEY_FILE_ENV = "WAGO_CONFIG_KEY_FILE"
+
+	keySize = 32
+)
+
+var (
+	// ErrKeyNotSet is returned while loading key without environment variables
+	ErrKeyNotSet = errors.New("wago: config key is not set, set " + KEY_ENV + " or " + KEY_FILE_ENV)
+)
+
+// IsEncrypted checks whether s is an encrypted value
+func IsEncrypted(s string) bool {
+	return strings.HasPrefix(s, PREFIX_V1)
+}
+
+// GenerateKey generates a random key encoded by base64
+func GenerateKey() (string, error) {
+	key := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// LoadKey loads key from WAGO_CONFIG_KEY or WAGO_CONFIG_KEY_FILE,
+// ErrKeyNotSet is returned while neither is set.
+func LoadKey() ([]byte, error) {
+	encoded, ok := os.LookupEnv(KEY_ENV)
+	if !ok {
+		file, ok := os.LookupEnv(KEY_FILE_ENV)
+		if !ok {
+			return nil, ErrKeyNotSet
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("wago: failed to read config key file, err=%s", err.Error())
+		}
+		encoded = string(data)
+	}
+
+	return ParseKey(encoded)
+}
+
+// ParseKey decodes key encoded by base64
+func ParseKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("wago: invalid config key, err=%s", err.Error())
+	}
+	if len(key) != keySize {
+		return nil, fmt.Errorf("wago: invalid config key, must be %d bytes, got %d", keySize, len(key))
+	}
+
+	return key, nil
+}
+
+// Encrypt encrypts plaintext to "enc:v1:..." value
+func Encrypt(key []byte, plaintext string) (string, error) {
+	gcm, err := newCipher(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+
+	return PREFIX_V1 + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts "enc:v1:..." value to plaintext
+func Decrypt(key []byte, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return "", errors.New("wago: not an encrypted value, missing prefix " + PREFIX_V1)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, PREFIX_V1))
+	if err != nil {
+		return "", fmt.Errorf("wago: invalid encrypted value, err=%s", err.Error())
+	}
+
+	gcm, err := newCipher(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("wago: invalid encrypted value, too short")
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", errors.New("wago: failed to decrypt value, wrong key or corrupted value")
+	}
+
+	return string(plaintext), nil
+}
+
+func newCipher(key []byte) (cipher.AEAD, error) {
+	if len(key) != keySize {
+		return nil, fmt.Errorf("wago: invalid config key, must be %d bytes, got %d", keySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,75 @@
+// Copyright 2019 - now The https://github.com/nvwa-io/wago Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func mustKey(t *testing.T) []byte {
+	encoded, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParseKey(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	key := mustKey(t)
+	value, err := Encrypt(key, "p@ssw0rd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(value) {
+		t.Fatalf("expect prefix %s, got %s", PREFIX_V1, value)
+	}
+
+	plaintext, err := Decrypt(key, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext != "p@ssw0rd" {
+		t.Fatalf("expect p@ssw0rd, got %s", plaintext)
+	}
+}
+
+func TestDecryptWithWrongKey(t *testing.T) {
+	value, err := Encrypt(mustKey(t), "p@ssw0rd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Decrypt(mustKey(t), value); err == nil {
+		t.Fatal("expect error while decrypting with wrong key")
+	}
+}
+
+func TestInvalidKeyLength(t *testing.T) {
+	short := make([]byte, 16)
+	if _, err := ParseKey(base64.StdEncoding.EncodeToString(short)); err == nil {
+		t.Fatal("expect error while parsing 16 bytes key")
+	}
+	if _, err := Encrypt(short, "p@ssw0rd"); err == nil {
+		t.Fatal("expect error while encrypting with 16 bytes key")
+	}
+	if _, err := Decrypt(short, PREFIX_V1+base64.StdEncoding.EncodeToString(make([]byte, 32))); err == nil {
+		t.Fatal("expect error while decrypting with 16 bytes key")
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2019 - now The https://github.com/nvwa-io/wago Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wago
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Structs are validated by validate tags, rules of github.com/go-playground/validator are supported, e.g:
+// Name     string `json:"name" validate:"required,min=3"`
+// Email    string `json:"email" validate:"omitempty,email"`
+// Role     string `json:"role" validate:"oneof=admin user"`
+// Password string `json:"password" validate:"required"`
+// Confirm  string `json:"confirm" validate:"eqfield=Password"`
+// request parameters of controller methods are validated after binding, refer to bind.go.
+// field path of errors is named by json, form, query, uri or header tag, e.g: address.city
+const (
+	TAG_VALIDATE = "validate"
+)
+
+var (
+	validateOnce sync.Once
+	validate     *validator.Validate
+
+	// tags naming field path, in order
+	fieldNameTags = []string{TAG_JSON, TAG_FORM, TAG_QUERY, TAG_URI, TAG_HEADER}
+
+	// messages of common rules, others are "{field} failed on rule {rule}"
+	ruleMessages = map[string]string{
+		"required": "%s is required",
+		"email":    "%s must be a valid email address",
+		"url":      "%s must be a valid URL",
+		"uuid":     "%s must be a valid UUID",
+		"oneof":    "%s must be one of [%s]",
+		"len":      "%s must have length %s",
+		"eq":       "%s must be equal to %s",
+		"ne":       "%s must not be equal to %s",
+		"gt":       "%s must be greater than %s",
+		"gte":      "%s must be greater than or equal to %s",
+		"lt":       "%s must be less than %s",
+		"lte":      "%s must be less than or equal to %s",
+		"eqfield":  "%s must be equal to %s",
+		"nefield":  "%s must not be equal to %s",
+		"gtfield":  "%s must be greater than %s",
+		"ltfield":  "%s must be less than %s",
+	}
+)
+
+// FieldError is a field error of request responded to client
+type FieldError struct {
+	// field path, e.g: address.city
+	Field string `json:"field"`
+
+	// failed rule, e.g: required
+	Rule    string `json:"rule,omitempty"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors is returned while validation fails, responded with 422 by DefaultErrorRenderer
+type ValidationErrors []FieldError
+
+func (t ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(t))
+	for _, e := range t {
+		msgs = append(msgs, e.Message)
+	}
+
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// Validator returns the validator used by wago, e.g: to register translations
+func Validator() *validator.Validate {
+	validateOnce.Do(func() {
+		validate = validator.New()
+		validate.SetTagName(TAG_VALIDATE)
+		validate.RegisterTagNameFunc(fieldName)
+	})
+
+	return validate
+}
+
+// RegisterValidation registers a custom rule, e.g:
+// wago.RegisterValidation("mobile", func(fl validator.FieldLevel) bool { ... })
+// it should be called before serving, it isn't thread-safe.
+func RegisterValidation(tag string, fn validator.Func, callValidationEvenIfNull ...bool) error {
+	return Validator().RegisterValidation(tag, fn, callValidationEvenIfNull...)
+}
+
+// RegisterStructValidation registers a cross-field rule of types, e.g:
+// wago.RegisterStructValidation(func(sl validator.StructLevel) { ... sl.ReportError(...) }, CreateUserReq{})
+// it should be called before serving, it isn't thread-safe.
+func RegisterStructValidation(fn validator.StructLevelFunc, types ...interface{}) {
+	Validator().RegisterStructValidation(fn, types...)
+}
+
+// Validate validates struct by validate tags, ValidationErrors is returned while failed
+func Validate(v interface{}) error {
+	err := Validator().Struct(v)
+	if err == nil {
+		return nil
+	}
+
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		return err
+	}
+
+	verrs := make(ValidationErrors, 0, len(errs))
+	for _, e := range errs {
+		verrs = append(verrs, newFieldError(e))
+	}
+
+	return verrs
+}
+
+func newFieldError(e validator.FieldError) FieldError {
+	// namespace is prefixed with struct name, e.g: CreateUserReq.address.city
+	field := e.Namespace()
+	if i := strings.Index(field, "."); i >= 0 {
+		field = field[i+1:]
+	}
+
+	msg := fmt.Sprintf("%s failed on rule %s", field, e.Tag())
+	switch format, ok := ruleMessages[e.Tag()]; {
+	case e.Tag() == "min" || e.Tag() == "max":
+		msg = sizeMessage(field, e)
+	case ok && strings.Count(format, "%s") == 2:
+		msg = fmt.Sprintf(format, field, strings.Replace(e.Param(), " ", ", ", -1))
+	case ok:
+		msg = fmt.Sprintf(format, field)
+	}
+
+	return FieldError{Field: field, Rule: e.Tag(), Message: msg}
+}
+
+// message of min/max depends on kind of field
+func sizeMessage(field string, e validator.FieldError) string {
+	bound := "at least"
+	if e.Tag() == "max" {
+		bound = "at most"
+	}
+
+	switch e.Kind() {
+	case reflect.String:
+		return fmt.Sprintf("%s must be %s %s characters", field, bound, e.Param())
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return fmt.Sprintf("%s must contain %s %s items", field, bound, e.Param())
+	}
+
+	return fmt.Sprintf("%s must be %s %s", field, bound, e.Param())
+}
+
+// name of field in error path
+func fieldName(f reflect.StructField) string {
+	for _, tag := range fieldNameTags {
+		name := strings.Split(f.Tag.Get(tag), ",")[0]
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+
+	return ""
+}
@@ -14,6 +14,8 @@
 package wago
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -22,109 +24,453 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/http/cgi"
+	"net/http/fcgi"
 	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
+const (
+	// default seconds to drain in-flight requests and run shutdown hooks
+	DEFAULT_SHUTDOWN_TIMEOUT = 30
+)
+
 var (
+	// default app, used by package-level functions
 	WagoApp *Wago
 )
 
 func init() {
-	WagoApp = NewWago()
+	// gin prints debug warnings while creating engine, stdout is the response of CGI request
+	if isCGI() {
+		gin.DefaultWriter = os.Stderr
+	}
+	WagoApp = New(AppConfig)
 }
 
-func NewWago() *Wago {
-	w := &Wago{
+// New creates a wago app with its own gin engine, router groups and configuration.
+// several apps can be served in one process, e.g: public API and internal API on different ports.
+func New(cfg *Config) *Wago {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+
+	t := &Wago{
 		Server: gin.New(),
+		config: cfg,
 	}
+	t.Server.Use(t.Recovery(), func(c *Context) {
+		c.Set(APP, t)
+	})
+
+	// CORS middleware is registered before routes, so that it's included in handler chain of every route,
+	// handler is built while booting and rebuilt while config is reloaded
+	t.Server.Use(func(c *Context) {
+		if h, ok := t.cors.Load().(gin.HandlerFunc); ok {
+			h(c)
+		}
+	})
 
-	return w
+	return t
+}
+
+// Deprecated: use New instead.
+func NewWago() *Wago {
+	return New(AppConfig)
 }
 
 type Wago struct {
 	// Use gin as http server
 	Server *gin.Engine
 
+	// configuration of current app, replaced while reloading
+	mu     sync.RWMutex
+	config *Config
+
+	// subscribers of config changes
+	configChangeHandlers []ConfigChangeHandler
+
+	// CORS middleware, rebuilt while config is reloaded
+	cors atomic.Value
+
 	// router groups for configuring HTTP request handler
 	routerGroups []*RouterGroup
+
+	// lifecycle hooks, refer to hooks.go
+	hooks map[string][]Hook
+
+	// structs bound to custom config sections
+	bindings []configBinding
+
+	// renderers of values returned by controller methods, refer to http_error.go
+	errorRenderer ErrorRenderer
+	responder     Responder
+
+	// make sure app is booted only once
+	bootOnce sync.Once
+	bootErr  error
 }
 
 func (t *Wago) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	WagoApp.Server.ServeHTTP(w, req)
+	t.Server.ServeHTTP(w, req)
+}
+
+// Config returns configuration of current app
+func (t *Wago) Config() *Config {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.config
+}
+
+// SetConfig replaces configuration of current app.
+// AllowOriginFunc registered by code is kept, and bound config structs are re-filled.
+// nothing is changed while any bound struct fails to decode.
+func (t *Wago) SetConfig(cfg *Config) error {
+	return t.swapConfig(cfg)
+}
+
+// Handler boots app and returns it as http.Handler,
+// e.g: to be served by a custom http.Server or httptest.
+// it panics while booting failed, call Boot first to handle the error.
+func (t *Wago) Handler() http.Handler {
+	if err := t.Boot(context.Background()); err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Use attaches a global middleware to the router of current app.
+func (t *Wago) Use(middleware ...MiddleWareHandler) gin.IRoutes {
+	return t.Server.Use(middleware...)
+}
+
+// Add router groups to current app
+func (t *Wago) AddRouterGroups(rgs ...*RouterGroup) {
+	t.routerGroups = append(t.routerGroups, rgs...)
+}
+
+// set CORS AllowOriginFunc of current app
+func (t *Wago) AllowOriginFunc(f func(string) bool) {
+	t.config.Server.Cors.AllowOriginFunc = f
 }
 
-func config() {
+// Boot validates config, configures logger, runs OnBoot hooks and registers routers.
+// app is booted only once, no matter Boot(), Handler() or Run() is called first.
+func (t *Wago) Boot(ctx context.Context) error {
+	t.bootOnce.Do(func() {
+		t.bootErr = t.boot(ctx)
+	})
+
+	return t.bootErr
+}
+
+func (t *Wago) boot(ctx context.Context) error {
+	cfg := t.Config()
+
+	// refuse to start with invalid configuration
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
 	// config gin engine running mode.
-	gin.SetMode(AppConfig.App.RunMode)
+	gin.SetMode(cfg.App.RunMode)
+	gin.DefaultWriter = consoleOutput(cfg.Server)
+
+	// config logger, notice: logger is shared by all apps in process
+	configLogger(cfg.Log)
+	configLogOutput(cfg.Log, consoleOutput(cfg.Server))
+	logger.AddHook(&secretRedactHook{app: t})
+	if workerID := preforkWorkerID(); workerID > 0 {
+		logger.AddFields(logger.Fields{LOG_FIELD_WORKER: workerID})
+	}
+
+	if err := t.runHooks(ctx, HOOK_BOOT); err != nil {
+		return err
+	}
+
+	// config HTTP Server CORS
+	t.cors.Store(newCorsHandler(cfg.Server.Cors))
+
+	t.registerRouters(cfg)
+
+	return nil
+}
 
+func (t *Wago) registerRouters(cfg *Config) {
 	// generate comment routers while RunMode is debug and RouterMode is "comment"
-	if AppConfig.App.RunMode == RUN_MODE_DEBUG &&
-		AppConfig.App.RouterMode == ROUTER_MODE_COMMENT {
-		ParseRouter(AppConfig.App.ControllerPath)
+	if cfg.App.RunMode == RUN_MODE_DEBUG &&
+		cfg.App.RouterMode == ROUTER_MODE_COMMENT {
+		ParseRouter(cfg.App.ControllerPath)
 	}
 
-	// register routers
-	for _, rg := range WagoApp.routerGroups {
-		rg.config()
+	for _, rg := range t.routerGroups {
+		rg.config(t)
+	}
+}
+
+// console output of logs, stdout is used by CGI response
+func consoleOutput(c Server) io.Writer {
+	if c.Mode == SERVER_MODE_CGI || isCGI() {
+		return os.Stderr
 	}
+	return os.Stdout
+}
+
+// whether process is spawned by CGI server, which sets GATEWAY_INTERFACE, refer to RFC 3875
+func isCGI() bool {
+	return os.Getenv("GATEWAY_INTERFACE") != ""
+}
 
-	// config logger
-	logger.SetFormatterByString(AppConfig.Log.Formatter)
-	logger.SetLevelByUint32(AppConfig.Log.Level)
-	logger.SetReportCaller(AppConfig.Log.LogMethodName)
-	if AppConfig.Log.Console {
+// config log output, file is rotated by lumberjack
+func configLogOutput(c Log, console io.Writer) {
+	if c.Console {
 		w := io.MultiWriter(
-			os.Stdout,
+			console,
 			&lumberjack.Logger{
-				Filename:   AppConfig.Log.Filename,
-				MaxSize:    AppConfig.Log.MaxSize,
-				MaxBackups: AppConfig.Log.MaxBackups,
-				MaxAge:     AppConfig.Log.MaxAge,
-				Compress:   AppConfig.Log.Compress,
+				Filename:   c.Filename,
+				MaxSize:    c.MaxSize,
+				MaxBackups: c.MaxBackups,
+				MaxAge:     c.MaxAge,
+				Compress:   c.Compress,
 			},
 		)
 		logger.SetOutput(w)
 	} else {
 		logger.SetOutput(&lumberjack.Logger{
-			Filename:   AppConfig.Log.Filename,
-			MaxSize:    AppConfig.Log.MaxSize,
-			MaxBackups: AppConfig.Log.MaxBackups,
-			MaxAge:     AppConfig.Log.MaxAge,
-			Compress:   AppConfig.Log.Compress,
+			Filename:   c.Filename,
+			MaxSize:    c.MaxSize,
+			MaxBackups: c.MaxBackups,
+			MaxAge:     c.MaxAge,
+			Compress:   c.Compress,
 		})
 	}
+}
 
-	// config HTTP Server CORS
-	WagoApp.Server.Use(cors.New(cors.Config{
-		AllowOrigins:     AppConfig.Server.Cors.AllowOrigins,
-		AllowMethods:     AppConfig.Server.Cors.AllowMethods,
-		AllowHeaders:     AppConfig.Server.Cors.AllowHeaders,
-		ExposeHeaders:    AppConfig.Server.Cors.ExposeHeaders,
-		AllowCredentials: AppConfig.Server.Cors.AllowCredentials,
-		AllowOriginFunc:  AppConfig.Server.Cors.AllowOriginFunc,
-		MaxAge:           time.Duration(AppConfig.Server.Cors.MaxAge) * time.Hour,
-	}))
-}
-
-// Boot Wago app
-func Serve() {
+// config logger settings which can be changed while running
+func configLogger(c Log) {
+	logger.SetFormatterByString(c.Formatter)
+	logger.SetLevelByUint32(c.Level)
+	logger.SetReportCaller(c.LogMethodName)
+}
 
-	// app configuration
-	config()
+// create CORS handler, do nothing while no origin is allowed
+func newCorsHandler(c Cors) gin.HandlerFunc {
+	if len(c.AllowOrigins) == 0 && c.AllowOriginFunc == nil {
+		return func(*Context) {}
+	}
+
+	return cors.New(cors.Config{
+		AllowOrigins:     c.AllowOrigins,
+		AllowMethods:     c.AllowMethods,
+		AllowHeaders:     c.AllowHeaders,
+		ExposeHeaders:    c.ExposeHeaders,
+		AllowCredentials: c.AllowCredentials,
+		AllowOriginFunc:  c.AllowOriginFunc,
+		MaxAge:           time.Duration(c.MaxAge) * time.Hour,
+	})
+}
+
+// Run boots and serves current app until ctx is done,
+// then gracefully shuts down HTTP server and runs AfterServe and OnShutdown hooks.
+// nil is returned while app is stopped gracefully.
+func (t *Wago) Run(ctx context.Context) error {
+	// prefork mode, refer to prefork_unix.go
+	if workerID := preforkWorkerID(); workerID == 0 && t.Config().Server.Prefork > 0 {
+		return t.runPrefork(ctx)
+	} else if workerID > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = withPreforkMaster(ctx)
+		defer cancel()
+	}
+
+	if err := t.Boot(ctx); err != nil {
+		return err
+	}
+
+	cfg := t.Config()
+	if cfg.Server.Mode == SERVER_MODE_CGI {
+		return t.serveCGI(ctx)
+	}
+	server, reloader, listeners, err := t.prepareServe(ctx, cfg)
+	if err != nil {
+		// OnBoot hooks have run, resources are released by OnShutdown hooks
+		t.runHooksAndLog(context.Background(), HOOK_SHUTDOWN)
+		return err
+	}
+
+	// reload config and certificate while config file changed or receiving SIGHUP
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	defer stopWatch()
+	go t.watch(watchCtx, reloader)
+
+	errCh := make(chan error, len(listeners))
+	for _, l := range listeners {
+		go func(l *namedListener) {
+			logger.Infof("%s is serving %s on %s %s", cfg.App.App, cfg.Server.Mode, l.Addr().Network(), l.Addr().String())
+			if cfg.Server.Mode == SERVER_MODE_FCGI {
+				errCh <- fcgi.Serve(l, t)
+				return
+			}
+			if l.tls {
+				// certificate is provided by TLSConfig.GetCertificate
+				errCh <- server.ServeTLS(l, "", "")
+				return
+			}
+			errCh <- server.Serve(l)
+		}(l)
+	}
+
+	// restart on SIGUSR2 except workers of prefork mode, which ignore it, refer to upgrade_unix.go
+	upgraded := make(chan struct{})
+	if preforkWorkerID() == 0 {
+		go t.watchUpgrade(watchCtx, listeners, upgraded)
+	}
+	notifyReady()
+
+	var serveErr error
+	select {
+	case err := <-errCh:
+		if err != nil && err != http.ErrServerClosed {
+			serveErr = err
+			logger.Errorf("%s stopped serving, err=%s", cfg.App.App, err.Error())
+		}
+	case <-ctx.Done():
+		logger.Infof("%s is shutting down", cfg.App.App)
+	case <-upgraded:
+		logger.Infof("%s is handed off to new process, shutting down", cfg.App.App)
+	}
+
+	// FastCGI listeners aren't tracked by HTTP server, in-flight requests aren't drained
+	if cfg.Server.Mode == SERVER_MODE_FCGI {
+		for _, l := range listeners {
+			l.Close()
+		}
+	}
+
+	if err := t.shutdown(server); serveErr == nil {
+		serveErr = err
+	}
+
+	return serveErr
+}
+
+// serve a single request of CGI environment, then run AfterServe and OnShutdown hooks.
+// console output is written to stderr, since stdout is the response.
+func (t *Wago) serveCGI(ctx context.Context) error {
+	if err := t.runHooks(ctx, HOOK_BEFORE_SERVE); err != nil {
+		t.runHooksAndLog(context.Background(), HOOK_SHUTDOWN)
+		return err
+	}
+
+	err := cgi.Serve(t)
+	if err != nil {
+		logger.Errorf("failed to serve CGI request, err=%s", err.Error())
+	}
+
+	t.runHooksAndLog(ctx, HOOK_AFTER_SERVE)
+	t.runHooksAndLog(ctx, HOOK_SHUTDOWN)
+
+	return err
+}
+
+// create HTTP server, bind listeners and run BeforeServe hooks, listeners are closed while failed
+func (t *Wago) prepareServe(ctx context.Context, cfg *Config) (*http.Server, *certReloader, []*namedListener, error) {
+	server := t.newHTTPServer()
+
+	var reloader *certReloader
+	if cfg.Server.usesTLS() {
+		// development certificate is used while RunMode is debug and no cert file is set
+		c, err := cfg.tlsConfig()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		var tlsConfig *tls.Config
+		tlsConfig, reloader, err = newTLSConfig(c)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to config TLS, err=%s", err.Error())
+		}
+		server.TLSConfig = tlsConfig
+	}
+	if err := configHTTP2(server, cfg.Server.HTTP2); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to config HTTP/2, err=%s", err.Error())
+	}
+
+	// bind listeners, errors like "address already in use" are returned to caller
+	listeners, err := t.listen(cfg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if err := t.runHooks(ctx, HOOK_BEFORE_SERVE); err != nil {
+		for _, l := range listeners {
+			l.Close()
+		}
+		return nil, nil, nil, err
+	}
 
-	// start gin server
-	server := &http.Server{
-		Addr: fmt.Sprintf("%s:%d", AppConfig.Server.Host, AppConfig.Server.Port),
-		//Handler: WagoApp.Server,
-		Handler: WagoApp,
-		// @TODO Config http server
+	return server, reloader, listeners, nil
+}
+
+// create HTTP server by [server] configuration
+func (t *Wago) newHTTPServer() *http.Server {
+	c := t.Config().Server
+	return &http.Server{
+		Addr:              fmt.Sprintf("%s:%d", c.Host, c.Port),
+		Handler:           t,
+		ReadTimeout:       time.Duration(c.ReadTimeout) * time.Second,
+		ReadHeaderTimeout: time.Duration(c.ReadHeaderTimeout) * time.Second,
+		WriteTimeout:      time.Duration(c.WriteTimeout) * time.Second,
+		IdleTimeout:       time.Duration(c.IdleTimeout) * time.Second,
+		MaxHeaderBytes:    c.MaxHeaderBytes,
 	}
+}
 
-	//engine.Use(Logger(), Recovery())
-	err := server.ListenAndServe()
+// gracefully shutdown HTTP server, then run AfterServe and OnShutdown hooks in order.
+// draining and hooks share the same timeout: Server.ShutdownTimeout
+func (t *Wago) shutdown(server *http.Server) error {
+	timeout := t.Config().Server.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = DEFAULT_SHUTDOWN_TIMEOUT
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	err := server.Shutdown(ctx)
 	if err != nil {
-		log.Fatalln(AppConfig.App, " finished, err=", err.Error())
+		logger.Errorf("failed to drain HTTP server, err=%s", err.Error())
+	}
+
+	t.runHooksAndLog(ctx, HOOK_AFTER_SERVE)
+	t.runHooksAndLog(ctx, HOOK_SHUTDOWN)
+	logger.Infof("%s finished", t.Config().App.App)
+
+	return err
+}
+
+// Run boots and serves default app until ctx is done, signals aren't handled,
+// e.g: to be supervised by errgroup:
+// g.Go(func() error { return wago.Run(ctx) })
+// nil is returned while app is stopped gracefully,
+// errors of booting or binding listener, e.g: "address already in use", are returned.
+func Run(ctx context.Context) error {
+	return WagoApp.Run(ctx)
+}
+
+// Boot Wago app, serve until SIGINT/SIGTERM is received,
+// returns after graceful shutdown, exits process while failed.
+func Serve() {
+	// wait for SIGINT/SIGTERM, e.g: Ctrl+C or kubernetes rolling update
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := Run(ctx); err != nil {
+		log.Fatalln(WagoApp.Config().App, " finished, err=", err.Error())
 	}
 }
 
@@ -132,15 +478,15 @@ func Serve() {
 // included in the handlers chain for every single request. Even 404, 405, static files...
 // For example, this is the right place for a logger or error management middleware.
 func Use(middleware ...MiddleWareHandler) gin.IRoutes {
-	return WagoApp.Server.Use(middleware...)
+	return WagoApp.Use(middleware...)
 }
 
 // Add router groups
 func AddRouterGroups(rgs ...*RouterGroup) {
-	WagoApp.routerGroups = append(WagoApp.routerGroups, rgs...)
+	WagoApp.AddRouterGroups(rgs...)
 }
 
 // set CORS AllowOriginFunc
 func AllowOriginFunc(f func(string) bool) {
-	AppConfig.Server.Cors.AllowOriginFunc = f
+	WagoApp.AllowOriginFunc(f)
 }
@@ -0,0 +1,66 @@
+// Copyright 2019 - now The https://github.com/nvwa-io/wago Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wago
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func corsRequest(app *Wago, origin string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/v1/hello", nil)
+	req.Header.Set("Origin", origin)
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+
+	return w
+}
+
+func TestCorsOnRegisteredRoute(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Log.Filename = t.TempDir() + "/wago.log"
+	cfg.Server.Cors.AllowOrigins = []string{"https://a.com"}
+
+	app := New(cfg)
+	app.Server.GET("/v1/hello", func(c *Context) {
+		c.String(http.StatusOK, "hello")
+	})
+	if err := app.Boot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	w := corsRequest(app, "https://a.com")
+	if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "https://a.com" {
+		t.Fatalf("allowed origin: status = %d, headers = %v", w.Code, w.Header())
+	}
+	if w := corsRequest(app, "https://b.com"); w.Code != http.StatusForbidden {
+		t.Fatalf("disallowed origin: status = %d", w.Code)
+	}
+
+	// origins are applied live while config is replaced
+	next := DefaultConfig()
+	next.Log.Filename = cfg.Log.Filename
+	next.Server.Cors.AllowOrigins = []string{"https://b.com"}
+	if err := app.SetConfig(next); err != nil {
+		t.Fatal(err)
+	}
+	if w := corsRequest(app, "https://b.com"); w.Header().Get("Access-Control-Allow-Origin") != "https://b.com" {
+		t.Fatalf("reloaded origin: status = %d, headers = %v", w.Code, w.Header())
+	}
+	if w := corsRequest(app, "https://a.com"); w.Code != http.StatusForbidden {
+		t.Fatalf("removed origin: status = %d", w.Code)
+	}
+}